@@ -0,0 +1,387 @@
+package validator
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+type ruleMeta struct {
+	// raw is the rule as written in the tag, for syntax errors
+	raw  string
+	name string
+	arg  string
+	key  bool
+	// container rules come before a dive and apply to the container itself,
+	// elem rules come after it and apply to every element
+	container bool
+	elem      bool
+	invalid   bool
+	// syntaxErr tells why an invalid rule is invalid
+	syntaxErr error
+	fn        RuleFunc
+	// sibling is the index of the field compared against by eqfield and friends
+	sibling []int
+	// bound is the index of the field a "$Field" argument of min or max names
+	bound []int
+}
+
+type fieldMeta struct {
+	index []int
+	name  string
+	// jsonName is the name in the json tag, or name without one
+	jsonName string
+	exported bool
+	// viaPtr marks fields promoted through an embedded pointer
+	viaPtr bool
+	nested bool
+	// elemNested marks slices, arrays and maps of structs
+	elemNested bool
+	tagged     bool
+	rules      []ruleMeta
+	// msg replaces the message of rule failures, from the <tag>_msg tag
+	msg string
+	// def is the field's default tag, see Options.SkipDefaults
+	def    string
+	hasDef bool
+}
+
+type structMeta struct {
+	fields []fieldMeta
+	// validatable is false when neither the struct nor anything nested in it
+	// has a tag, so validating it can return right away
+	validatable bool
+}
+
+func (m *structMeta) hasField(name string) bool {
+	for _, field := range m.fields {
+		if field.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type structKey struct {
+	typ     reflect.Type
+	tagName string
+}
+
+var regexpCache sync.Map // string -> *regexp.Regexp
+
+func (v *Validator) structMeta(typeStruct reflect.Type, tagName string) *structMeta {
+	key := structKey{typ: typeStruct, tagName: tagName}
+	if meta, ok := v.cache.Load(key); ok {
+		return meta.(*structMeta)
+	}
+	meta, _ := v.cache.LoadOrStore(key, v.parseStructMeta(typeStruct, tagName))
+	return meta.(*structMeta)
+}
+
+func (v *Validator) parseStructMeta(typeStruct reflect.Type, tagName string) *structMeta {
+	meta := &structMeta{}
+	// VisibleFields applies Go's promotion rules: fields of embedded structs
+	// are listed after the embedded field, and shadowed or ambiguous ones are left out
+	for _, typeField := range reflect.VisibleFields(typeStruct) {
+		if !promoted(typeStruct, typeField.Index) {
+			continue
+		}
+
+		fieldType := typeField.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		// valueType is the type the rules check, the element type of a container
+		valueType := fieldType
+		if isContainerKind(fieldType.Kind()) {
+			valueType = fieldType.Elem()
+			if valueType.Kind() == reflect.Ptr {
+				valueType = valueType.Elem()
+			}
+		}
+
+		field := fieldMeta{
+			index:    typeField.Index,
+			name:     typeField.Name,
+			jsonName: jsonName(typeField),
+			exported: typeField.IsExported(),
+			viaPtr:   viaPtr(typeStruct, typeField.Index),
+			nested:   fieldType.Kind() == reflect.Struct && fieldType != timeType && !typeField.Anonymous,
+		}
+		_, field.elemNested = structElem(fieldType)
+
+		if validateTag := typeField.Tag.Get(tagName); validateTag != "" {
+			field.tagged = true
+			field.msg = typeField.Tag.Get(tagName + "_msg")
+			field.def, field.hasDef = typeField.Tag.Lookup("default")
+			var seen ruleSet
+			rules := strings.Split(validateTag, ";")
+			dive := -1
+			if isContainerKind(fieldType.Kind()) {
+				for i, rule := range rules {
+					if strings.TrimSpace(rule) == "dive" {
+						dive = i
+						break
+					}
+				}
+			}
+			for i, rule := range rules {
+				rule = strings.TrimSpace(rule)
+				meta := ruleMeta{raw: rule}
+				// rules prefixed with "key=" or "key:" apply to the keys of a map
+				// instead of its values; "value=" and "value:" spell out the default,
+				// and keep min and max on the values instead of the number of entries
+				var target bool
+				meta.key, target, rule = cutTarget(rule)
+				meta.name, meta.arg, _ = parseRule(rule)
+				if name := v.resolveAlias(meta.name); name != meta.name {
+					rule = name + strings.TrimPrefix(rule, meta.name)
+					meta.name = name
+				}
+				meta.container = !meta.key && i < dive
+				meta.elem = !meta.key && dive >= 0 && i > dive
+				// without a dive, len, minlen and maxlen bound the number of elements
+				// of a container; min and max do too for a []byte, such as a
+				// json.RawMessage, and the entries of a map, rather than each byte or
+				// value. A "value:" prefix keeps a rule on the values.
+				if dive < 0 && !target && isContainerKind(fieldType.Kind()) &&
+					(isContainerRule(meta.name) || isSizeRule(meta.name) && (isByteSlice(fieldType) || fieldType.Kind() == reflect.Map)) {
+					meta.container = true
+				}
+				// a rule repeated in one tag is a mistake, only the first one applies;
+				// a size bound and a "value:" rule of the same name may go together
+				scope := ""
+				if meta.key {
+					scope = "key"
+				} else if meta.container && dive < 0 && isSizeRule(meta.name) {
+					scope = "size"
+				}
+				duplicate := !seen.add(meta.name, scope)
+				switch {
+				case meta.key:
+					meta.invalid = fieldType.Kind() != reflect.Map || !v.ruleSyntaxValid(rule, fieldType.Key())
+				case target && fieldType.Kind() != reflect.Map:
+					meta.invalid = true
+				case meta.name == "dive":
+					meta.invalid = !isContainerKind(fieldType.Kind()) || !v.ruleSyntaxValid(rule, valueType)
+				case meta.container:
+					// only builtins that bound the number of elements apply here
+					meta.invalid = !ruleFits(rule, fieldType)
+				case meta.name == "trim":
+					// trim works on the field itself, not on elements
+					meta.invalid = fieldType.Kind() != reflect.String || !v.ruleSyntaxValid(rule, valueType)
+				case v.isFieldBound(meta.name, meta.arg):
+					meta.bound, meta.invalid = boundField(typeStruct, fieldType, meta.arg[1:])
+				default:
+					meta.invalid = !v.ruleSyntaxValid(rule, valueType)
+				}
+				name := meta.name
+				if r, ok := v.rule(name); ok {
+					meta.fn = r.fn
+					// the kind of an interface is only known once it holds a value
+					if r.builtin && valueType.Kind() == reflect.Interface {
+						meta.fn = dynamicRule(name, r.fn)
+					}
+				} else if isFieldRule(name) && !meta.invalid && !meta.key {
+					meta.sibling, meta.invalid = siblingField(typeStruct, fieldType, name, meta.arg)
+				} else if name == "required_if" && !meta.invalid && !meta.key {
+					meta.sibling, meta.invalid = conditionField(typeStruct, meta.arg)
+				}
+				meta.invalid = meta.invalid || duplicate
+				if meta.invalid {
+					_, builtin := builtinRules[name]
+					_, registered := v.rule(name)
+					meta.syntaxErr = errRuleSyntax(meta.raw, i+1, builtin || registered)
+				}
+				field.rules = append(field.rules, meta)
+			}
+		}
+
+		meta.fields = append(meta.fields, field)
+	}
+	meta.validatable = hasRules(typeStruct, tagName, map[reflect.Type]bool{})
+	return meta
+}
+
+// hasRules reports whether typeStruct or a struct nested in it has a field
+// tagged with tagName. Interface fields count too, since whatever they hold
+// is only known at run time. seen breaks cycles of self-referencing types.
+func hasRules(typeStruct reflect.Type, tagName string, seen map[reflect.Type]bool) bool {
+	if seen[typeStruct] {
+		return false
+	}
+	seen[typeStruct] = true
+	for _, typeField := range reflect.VisibleFields(typeStruct) {
+		if !promoted(typeStruct, typeField.Index) {
+			continue
+		}
+		if typeField.Tag.Get(tagName) != "" {
+			return true
+		}
+		if !typeField.IsExported() {
+			continue
+		}
+		fieldType := typeField.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		switch {
+		case fieldType.Kind() == reflect.Interface:
+			return true
+		case fieldType.Kind() == reflect.Struct && fieldType != timeType && !typeField.Anonymous:
+			if hasRules(fieldType, tagName, seen) {
+				return true
+			}
+		}
+		if elemType, ok := structElem(fieldType); ok && hasRules(elemType, tagName, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+func isByteSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// structElem returns the struct type held by a slice, array or map type,
+// through a pointer if need be.
+func structElem(t reflect.Type) (reflect.Type, bool) {
+	if !isContainerKind(t.Kind()) {
+		return nil, false
+	}
+	elemType := t.Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	return elemType, elemType.Kind() == reflect.Struct && elemType != timeType
+}
+
+// siblingField looks up the field named arg that a cross-field rule compares
+// against. The rule is invalid unless that field exists and has the same type
+// as the rule's own field, pointers aside, and that type supports the comparison.
+func siblingField(typeStruct, fieldType reflect.Type, name, arg string) (index []int, invalid bool) {
+	sibling, ok := typeStruct.FieldByName(arg)
+	if !ok || !sibling.IsExported() {
+		return nil, true
+	}
+	siblingType := sibling.Type
+	if siblingType.Kind() == reflect.Ptr {
+		siblingType = siblingType.Elem()
+	}
+	if siblingType != fieldType {
+		return nil, true
+	}
+	if name == "eqfield" || name == "nefield" {
+		return sibling.Index, !fieldType.Comparable()
+	}
+	return sibling.Index, !isOrderedType(fieldType)
+}
+
+// conditionField looks up the field a "required_if:Field value" rule
+// depends on. The rule is invalid unless that field exists and is a string,
+// bool or number, pointers aside, that value parses as.
+func conditionField(typeStruct reflect.Type, arg string) (index []int, invalid bool) {
+	args := strings.Fields(arg)
+	sibling, ok := typeStruct.FieldByName(args[0])
+	if !ok || !sibling.IsExported() {
+		return nil, true
+	}
+	siblingType := sibling.Type
+	if siblingType.Kind() == reflect.Ptr {
+		siblingType = siblingType.Elem()
+	}
+	return sibling.Index, !parsesAs(siblingType.Kind(), args[1])
+}
+
+// jsonName returns the name encoding/json uses for field, falling back to
+// the Go name for fields without one or left out with "-".
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" && !strings.HasPrefix(field.Tag.Get("json"), "-,") {
+		return field.Name
+	}
+	return name
+}
+
+// cutTarget strips the key or value target prefix off a map rule; target
+// reports whether there was one.
+func cutTarget(rule string) (key, target bool, rest string) {
+	for _, prefix := range []string{"key=", "key:"} {
+		if rest, ok := strings.CutPrefix(rule, prefix); ok {
+			return true, true, rest
+		}
+	}
+	for _, prefix := range []string{"value=", "value:"} {
+		if rest, ok := strings.CutPrefix(rule, prefix); ok {
+			return false, true, rest
+		}
+	}
+	return false, false, rule
+}
+
+// isFieldBound reports whether arg names a field, as in "min:$MinAge", for
+// the built-in min and max.
+func (v *Validator) isFieldBound(name, arg string) bool {
+	if name != "min" && name != "max" || !strings.HasPrefix(arg, "$") {
+		return false
+	}
+	r, ok := v.rule(name)
+	return ok && r.builtin
+}
+
+// boundField looks up the field a "$Field" bound names. Both it and the
+// field the rule is on must be integers, pointers aside.
+func boundField(typeStruct, fieldType reflect.Type, name string) (index []int, invalid bool) {
+	bound, ok := typeStruct.FieldByName(name)
+	if !ok || !bound.IsExported() || !isIntegerKind(fieldType.Kind()) {
+		return nil, true
+	}
+	boundType := bound.Type
+	if boundType.Kind() == reflect.Ptr {
+		boundType = boundType.Elem()
+	}
+	return bound.Index, !isIntegerKind(boundType.Kind())
+}
+
+// viaPtr reports whether the field at index is reached through an embedded
+// pointer.
+func viaPtr(typeStruct reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		typeStruct = typeStruct.Field(i).Type
+		if typeStruct.Kind() == reflect.Ptr {
+			return true
+		}
+	}
+	return false
+}
+
+// promoted reports whether the field at index is declared on typeStruct
+// itself or reached only through exported embedded structs.
+func promoted(typeStruct reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		embedded := typeStruct.Field(i)
+		if !embedded.IsExported() {
+			return false
+		}
+		typeStruct = embedded.Type
+		if typeStruct.Kind() == reflect.Ptr {
+			typeStruct = typeStruct.Elem()
+		}
+	}
+	return true
+}
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexpCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexpCache.Store(pattern, re)
+	return re, nil
+}
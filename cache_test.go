@@ -0,0 +1,95 @@
+package validator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+type benchRequest struct {
+	Name    string   `validate:"min:3;max:32"`
+	Email   string   `validate:"regexp:^[^@]+@[^@]+$"`
+	Age     int      `validate:"min:18;max:130"`
+	Role    string   `validate:"in:admin,user,guest"`
+	Tags    []string `validate:"maxlen:5;min:1"`
+	Address Address
+}
+
+var benchValue = benchRequest{
+	Name:    "gopher",
+	Email:   "gopher@example.com",
+	Age:     42,
+	Role:    "user",
+	Tags:    []string{"a", "b"},
+	Address: Address{Zip: "12345", City: Location{Code: "abc"}},
+}
+
+func TestStructMetaCached(t *testing.T) {
+	typ := reflect.TypeOf(benchValue)
+	assert.Same(t, defaultValidator.structMeta(typ, defaultTagName), defaultValidator.structMeta(typ, defaultTagName))
+	assert.NotSame(t, defaultValidator.structMeta(typ, defaultTagName), defaultValidator.structMeta(typ, "args"))
+
+	meta := defaultValidator.structMeta(typ, defaultTagName)
+	assert.Len(t, meta.fields, 6)
+	assert.Len(t, meta.fields[0].rules, 2)
+	assert.Equal(t, "min", meta.fields[0].rules[0].name)
+	assert.Equal(t, "3", meta.fields[0].rules[0].arg)
+	assert.Equal(t, "max", meta.fields[0].rules[1].name)
+	assert.Equal(t, "32", meta.fields[0].rules[1].arg)
+	assert.True(t, meta.fields[5].nested)
+	assert.False(t, meta.fields[5].tagged)
+}
+
+type plainRequest struct {
+	ID      int
+	Name    string
+	Tags    []string
+	Created struct {
+		By string
+		At int64
+	}
+	Next *plainRequest
+}
+
+func TestStructMetaValidatable(t *testing.T) {
+	assert.True(t, defaultValidator.structMeta(reflect.TypeOf(benchValue), defaultTagName).validatable)
+	assert.False(t, defaultValidator.structMeta(reflect.TypeOf(plainRequest{}), defaultTagName).validatable)
+	// tags are only found under a nested field
+	assert.True(t, defaultValidator.structMeta(reflect.TypeOf(struct{ Address Address }{}), defaultTagName).validatable)
+	assert.False(t, defaultValidator.structMeta(reflect.TypeOf(struct{ Address Address }{}), "args").validatable)
+	assert.True(t, defaultValidator.structMeta(reflect.TypeOf(struct{ Payload any }{}), defaultTagName).validatable)
+	assert.True(t, defaultValidator.structMeta(reflect.TypeOf(struct{ Items []*Address }{}), defaultTagName).validatable)
+	assert.False(t, defaultValidator.structMeta(reflect.TypeOf(struct{ Items map[string]plainRequest }{}), defaultTagName).validatable)
+	assert.NoError(t, Validate(plainRequest{Next: &plainRequest{}}))
+}
+
+func BenchmarkValidate(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = Validate(benchValue)
+	}
+}
+
+func BenchmarkBatchValidator(b *testing.B) {
+	batch := defaultValidator.NewBatch(Options{Deep: true})
+	for i := 0; i < b.N; i++ {
+		_ = batch.Validate(benchValue)
+	}
+}
+
+func BenchmarkValidateUncached(b *testing.B) {
+	types := []reflect.Type{reflect.TypeOf(benchValue), reflect.TypeOf(Address{}), reflect.TypeOf(Location{})}
+	for i := 0; i < b.N; i++ {
+		for _, typ := range types {
+			defaultValidator.cache.Delete(structKey{typ: typ, tagName: defaultTagName})
+		}
+		regexpCache.Delete("^[^@]+@[^@]+$")
+		_ = Validate(benchValue)
+	}
+}
+
+func BenchmarkValidateTagless(b *testing.B) {
+	v := plainRequest{ID: 1, Name: "gopher", Tags: []string{"a", "b"}}
+	for i := 0; i < b.N; i++ {
+		_ = Validate(v)
+	}
+}
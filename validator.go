@@ -1,22 +1,68 @@
 package validator
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"go/token"
+	"net/mail"
+	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 var ErrNotStruct = errors.New("wrong argument given, should be a struct")
 var ErrInvalidValidatorSyntax = errors.New("invalid validator syntax")
+
+// ErrEmptyRule and ErrUnknownRule tell apart two common tag mistakes, an
+// empty rule as left by a stray or trailing ';' and a rule name that is
+// neither built in nor registered. Both wrap ErrInvalidValidatorSyntax.
+var ErrEmptyRule = fmt.Errorf("%w: empty rule", ErrInvalidValidatorSyntax)
+var ErrUnknownRule = fmt.Errorf("%w: unknown rule", ErrInvalidValidatorSyntax)
 var ErrValidateForUnexportedFields = errors.New("validation for unexported field is not allowed")
 var ErrInvalidatedField = errors.New("field invalidated")
 var ErrUnsupportedType = errors.New("type not supported")
+var ErrNotSlice = errors.New("wrong argument given, should be a slice or array")
+var ErrUnknownField = errors.New("unknown field")
+var ErrMaxDepth = errors.New("maximum nesting depth exceeded")
+
+// DefaultMaxDepth is how many levels of nested structs validation descends
+// when Options.MaxDepth is not set.
+const DefaultMaxDepth = 32
 
 type ValidationError struct {
 	FieldName string
 	Err       error
+	// Message is the custom message from the field's validate_msg tag, if any,
+	// or from the Validator's MessageFunc. It is shown instead of Err.
+	Message string
+}
+
+func (v ValidationError) message() string {
+	if v.Message != "" {
+		return v.Message
+	}
+	if v.Err == nil {
+		return ""
+	}
+	return v.Err.Error()
+}
+
+func (v ValidationError) Error() string {
+	return fmt.Sprintf("[%s]: %s", v.FieldName, v.message())
+}
+
+// Unwrap returns Err, so errors.Is and errors.As see through a single
+// ValidationError to sentinels such as ErrInvalidatedField.
+func (v ValidationError) Unwrap() error {
+	return v.Err
 }
 
 type ValidationErrors []ValidationError
@@ -24,203 +70,2402 @@ type ValidationErrors []ValidationError
 func (v ValidationErrors) Error() string {
 	var sb strings.Builder
 	for _, err := range v {
-		if errors.Is(err.Err, ErrInvalidValidatorSyntax) {
-			sb.WriteString(err.Err.Error())
-		} else if errors.Is(err.Err, ErrValidateForUnexportedFields) {
-			sb.WriteString(err.Err.Error())
-		} else {
-			sb.WriteString(fmt.Sprintf("[%s]: %s\n", err.FieldName, err.Err.Error()))
+		sb.WriteString(err.Error())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Filter returns the errors reported for fieldName, in order, or nil if the
+// field did not fail.
+func (v ValidationErrors) Filter(fieldName string) ValidationErrors {
+	var errs ValidationErrors
+	for _, err := range v {
+		if err.FieldName == fieldName {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// HasField reports whether fieldName failed validation.
+func (v ValidationErrors) HasField(fieldName string) bool {
+	for _, err := range v {
+		if err.FieldName == fieldName {
+			return true
+		}
+	}
+	return false
+}
+
+// Is reports whether any of the errors matches target, so that
+// errors.Is(err, ErrInvalidatedField) works on the result of Validate.
+func (v ValidationErrors) Is(target error) bool {
+	for _, err := range v {
+		if errors.Is(err.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As sets target to the first error that matches it, e.g. the *RuleError of
+// the first field rejected by a rule. A *ValidationError target gets the
+// first error as a whole.
+func (v ValidationErrors) As(target any) bool {
+	if ve, ok := target.(*ValidationError); ok && len(v) > 0 {
+		*ve = v[0]
+		return true
+	}
+	for _, err := range v {
+		if errors.As(err.Err, target) {
+			return true
 		}
 	}
+	return false
+}
+
+func (v ValidationError) kind() string {
+	switch {
+	case errors.Is(v.Err, ErrInvalidValidatorSyntax):
+		return "syntax"
+	case errors.Is(v.Err, ErrValidateForUnexportedFields):
+		return "unexported"
+	case errors.Is(v.Err, ErrUnsupportedType):
+		return "unsupported"
+	}
+	return "invalid"
+}
+
+// MarshalJSON encodes the error as {"field": ..., "kind": ..., "error": ...}.
+// Kind is "invalid" for values rejected by a rule, and "syntax", "unexported"
+// or "unsupported" for problems with the tag or the field itself.
+func (v ValidationError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Field string `json:"field"`
+		Kind  string `json:"kind"`
+		Error string `json:"error"`
+	}{
+		Field: v.FieldName,
+		Kind:  v.kind(),
+		Error: v.message(),
+	})
+}
+
+// MarshalJSON encodes the errors as a JSON array, which is empty rather than
+// null when there are none.
+func (v ValidationErrors) MarshalJSON() ([]byte, error) {
+	if v == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]ValidationError(v))
+}
+
+// RuleError is returned for a value rejected by a rule. It wraps
+// ErrInvalidatedField, so errors.Is keeps matching the sentinel.
+type RuleError struct {
+	Rule  string
+	Arg   string
+	Value any
+	// detail replaces the "value ..." part of the message when set
+	detail string
+}
+
+func (e *RuleError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(ErrInvalidatedField.Error())
+	sb.WriteString(": ")
+	sb.WriteString(e.Rule)
+	if e.Arg != "" {
+		sb.WriteString(":")
+		sb.WriteString(e.Arg)
+	}
+	sb.WriteString(" failed")
+	if str, ok := e.Value.(string); ok && e.detail == "" {
+		fmt.Fprintf(&sb, " (value %q)", str)
+	} else if e.detail != "" {
+		fmt.Fprintf(&sb, " (%s)", e.detail)
+	} else if e.Value != nil {
+		fmt.Fprintf(&sb, " (value %v)", e.Value)
+	}
 	return sb.String()
 }
 
-func validateStringLen(str string, validateTag string) error {
-	splitted := strings.Split(validateTag, ":")
-	length, _ := strconv.Atoi(splitted[1])
+func (e *RuleError) Unwrap() error {
+	return ErrInvalidatedField
+}
+
+func errRuleFailed(name, arg string, value any) error {
+	return &RuleError{Rule: name, Arg: arg, Value: value}
+}
+
+// errLengthFailed reports a length bound; Value holds the length.
+func errLengthFailed(name, arg string, length int) error {
+	return &RuleError{Rule: name, Arg: arg, Value: length, detail: fmt.Sprintf("length %d", length)}
+}
+
+// errSyntax reports the malformed rule raw as written in the tag.
+func errSyntax(raw string) error {
+	return fmt.Errorf("%w: %q", ErrInvalidValidatorSyntax, raw)
+}
+
+// errRuleSyntax explains why raw, the rule at position (from 1) in its tag,
+// is invalid; known reports whether the rule name exists.
+func errRuleSyntax(raw string, position int, known bool) error {
+	if strings.TrimSpace(raw) == "" {
+		return fmt.Errorf("%w at position %d", ErrEmptyRule, position)
+	}
+	if !known {
+		return fmt.Errorf("%w: %q", ErrUnknownRule, raw)
+	}
+	return errSyntax(raw)
+}
+
+func parseRule(rule string) (name, arg string, hasArg bool) {
+	return strings.Cut(strings.TrimSpace(rule), ":")
+}
+
+// splitList splits an in/notin argument on commas. An escaped comma (\,) is
+// kept as part of the candidate, and \\ stands for a single backslash.
+// Spaces around each candidate are trimmed, so "in:a, b" lists "a" and "b";
+// the validated value itself is compared as is, spaces included.
+func splitList(arg string) []string {
+	if !strings.Contains(arg, `\`) {
+		items := strings.Split(arg, ",")
+		for i, item := range items {
+			items[i] = strings.TrimSpace(item)
+		}
+		return items
+	}
+	var items []string
+	var sb strings.Builder
+	for i := 0; i < len(arg); i++ {
+		switch {
+		case arg[i] == '\\' && i+1 < len(arg) && (arg[i+1] == ',' || arg[i+1] == '\\'):
+			i++
+			sb.WriteByte(arg[i])
+		case arg[i] == ',':
+			items = append(items, strings.TrimSpace(sb.String()))
+			sb.Reset()
+		default:
+			sb.WriteByte(arg[i])
+		}
+	}
+	return append(items, strings.TrimSpace(sb.String()))
+}
+
+// len, min and max on strings count bytes, so "café" has length 5. Use
+// runemin and runemax to bound the number of characters (runes) instead, and
+// gt, gte, lt and lte to bound the value itself.
+func validateStringLen(str string, arg string) error {
+	length, _ := strconv.Atoi(arg)
 	if len(str) != length {
-		return ErrInvalidatedField
+		return errLengthFailed("len", arg, len(str))
 	}
 	return nil
 }
 
-func validateStringMinMax(str string, validateTag string) error {
-	splitted := strings.Split(validateTag, ":")
-	length, _ := strconv.Atoi(splitted[1])
-	switch splitted[0] {
+func validateStringMinMax(str string, name, arg string) error {
+	length, _ := strconv.Atoi(arg)
+	switch name {
 	case "min":
 		if len(str) < length {
-			return ErrInvalidatedField
+			return errLengthFailed(name, arg, len(str))
 		}
 	case "max":
 		if len(str) > length {
-			return ErrInvalidatedField
+			return errLengthFailed(name, arg, len(str))
 		}
 	}
 	return nil
 }
 
-func validateStringIn(str string, validateTag string) error {
-	splitted := strings.Split(validateTag, ":")
-	allowed := strings.Split(splitted[1], ",")
-	for _, s := range allowed {
-		if s == str {
-			return nil
+func validateStringRuneMinMax(str string, name, arg string) error {
+	length, _ := strconv.Atoi(arg)
+	count := utf8.RuneCountInString(str)
+	switch name {
+	case "runemin":
+		if count < length {
+			return errLengthFailed(name, arg, count)
+		}
+	case "runemax":
+		if count > length {
+			return errLengthFailed(name, arg, count)
 		}
 	}
-	return ErrInvalidatedField
+	return nil
+}
+
+// validateStringCompare compares str with arg byte-wise, as Go's < does, so
+// "abc" passes gte:abb. It bounds the value, unlike min and max which bound
+// the length.
+func validateStringCompare(str string, name, arg string) error {
+	var ok bool
+	switch name {
+	case "gt":
+		ok = str > arg
+	case "gte":
+		ok = str >= arg
+	case "lt":
+		ok = str < arg
+	case "lte":
+		ok = str <= arg
+	}
+	if !ok {
+		return errRuleFailed(name, arg, str)
+	}
+	return nil
 }
 
-func validateIntIn(num int, validateTag string) error {
-	splitted := strings.Split(validateTag, ":")
-	allowed := strings.Split(splitted[1], ",")
+func validateStringIn(str string, arg string) error {
+	allowed := splitList(arg)
 	for _, s := range allowed {
-		i, err := strconv.Atoi(s)
-		if err != nil {
-			return err
-		}
-		if i == num {
+		if s == str {
 			return nil
 		}
 	}
-	return ErrInvalidatedField
+	return errRuleFailed("in", arg, str)
 }
 
-func validateSyntax(validateTag string) bool {
-	tags := strings.Split(validateTag, ";")
-	for _, tag := range tags {
-		splitted := strings.Split(tag, ":")
-		if len(splitted) < 2 {
-			return true
+func validateStringNotIn(str string, arg string) error {
+	if validateStringIn(str, arg) == nil {
+		return errRuleFailed("notin", arg, str)
+	}
+	return nil
+}
+
+func validateStringRegexp(str string, pattern string) error {
+	re, err := compileRegexp(pattern)
+	if err != nil {
+		return err
+	}
+	if !re.MatchString(str) {
+		return errRuleFailed("regexp", pattern, str)
+	}
+	return nil
+}
+
+// validateStringEmail accepts a bare addr-spec such as "user@example.com".
+// Display names and angle brackets ("Bob <bob@example.com>") are rejected,
+// since the field is expected to hold the address itself.
+func validateStringEmail(str string) error {
+	addr, err := mail.ParseAddress(str)
+	if err != nil || addr.Address != str {
+		return errRuleFailed("email", "", str)
+	}
+	return nil
+}
+
+// validateStringURL accepts absolute URLs with both a scheme and a host.
+func validateStringURL(str string) error {
+	u, err := url.ParseRequestURI(str)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return errRuleFailed("url", "", str)
+	}
+	return nil
+}
+
+// validateStringClass checks that every rune of str is in the character
+// class of name: letters for alpha, letters and digits for alphanumeric,
+// digits for numeric. The empty string has no characters to check and is
+// rejected by all three; combine them with omitempty to allow it.
+func validateStringClass(str string, name string) error {
+	if str == "" {
+		return errRuleFailed(name, "", str)
+	}
+	for _, r := range str {
+		var ok bool
+		switch name {
+		case "alpha":
+			ok = unicode.IsLetter(r)
+		case "alphanumeric":
+			ok = unicode.IsLetter(r) || unicode.IsDigit(r)
+		case "numeric":
+			ok = unicode.IsDigit(r)
 		}
-		switch splitted[0] {
-		case "in":
-			if len(splitted) < 2 || len(splitted[1]) == 0 {
-				return true
-			}
-		case "len", "min", "max":
-			if len(splitted) != 2 || len(splitted[1]) == 0 {
-				return true
-			}
-			if _, err := strconv.Atoi(splitted[1]); err != nil {
-				return true
-			}
+		if !ok {
+			return errRuleFailed(name, "", str)
 		}
 	}
-	return false
+	return nil
 }
 
-func validateIntMinMax(num int, validateTag string) error {
-	splitted := strings.Split(validateTag, ":")
-	length, _ := strconv.Atoi(splitted[1])
-	switch splitted[0] {
-	case "min":
-		if num < length {
-			return ErrInvalidatedField
-		}
-	case "max":
-		if num > length {
-			return ErrInvalidatedField
+// validateStringUUID accepts the canonical 8-4-4-4-12 form of a UUID, such
+// as "123e4567-e89b-12d3-a456-426614174000", in either letter case. The
+// version and variant bits are not checked, and braces or a "urn:uuid:"
+// prefix are rejected.
+func validateStringUUID(str string) error {
+	if len(str) != 36 {
+		return errRuleFailed("uuid", "", str)
+	}
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return errRuleFailed("uuid", "", str)
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return errRuleFailed("uuid", "", str)
+			}
 		}
 	}
 	return nil
 }
 
-func validateString(str string, validateTag string) error {
-	switch strings.Split(validateTag, ":")[0] {
-	case "in":
-		if err := validateStringIn(str, validateTag); err != nil {
-			return err
+// passwordClasses lists the requirements a password rule may set, in the
+// order they are checked.
+var passwordClasses = []string{"min", "upper", "lower", "digit", "special"}
+
+// parsePasswordSpec parses the "min=8,upper=1" argument of a password rule
+// into the required count per class.
+func parsePasswordSpec(arg string) (map[string]int, error) {
+	spec := make(map[string]int)
+	for _, item := range strings.Split(arg, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return nil, errSyntax(item)
 		}
-	case "len":
-		if err := validateStringLen(str, validateTag); err != nil {
-			return err
+		known := false
+		for _, class := range passwordClasses {
+			known = known || class == key
 		}
-	case "min", "max":
-		if err := validateStringMinMax(str, validateTag); err != nil {
-			return err
+		count, err := strconv.Atoi(value)
+		if !known || err != nil || count < 0 {
+			return nil, errSyntax(item)
 		}
+		spec[key] = count
 	}
-	return nil
+	return spec, nil
 }
 
-func validateInt(num int, validateTag string) error {
-	switch strings.Split(validateTag, ":")[0] {
-	case "in":
-		if err := validateIntIn(num, validateTag); err != nil {
-			return err
+// validateStringPassword checks str against the counts of arg: min is the
+// number of characters, upper, lower, digit and special the number of
+// characters of that class, special meaning punctuation and symbols. The
+// error names the first unmet requirement and never includes the password.
+func validateStringPassword(str string, arg string) error {
+	spec, err := parsePasswordSpec(arg)
+	if err != nil {
+		return err
+	}
+	counts := map[string]int{"min": utf8.RuneCountInString(str)}
+	for _, r := range str {
+		switch {
+		case unicode.IsUpper(r):
+			counts["upper"]++
+		case unicode.IsLower(r):
+			counts["lower"]++
+		case unicode.IsDigit(r):
+			counts["digit"]++
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			counts["special"]++
 		}
-	case "min", "max":
-		if err := validateIntMinMax(num, validateTag); err != nil {
-			return err
+	}
+	for _, class := range passwordClasses {
+		if want, ok := spec[class]; ok && counts[class] < want {
+			return &RuleError{Rule: "password", Arg: arg, detail: fmt.Sprintf("%s: %d of %d", class, counts[class], want)}
 		}
 	}
 	return nil
 }
 
-func Validate(v any) error {
-	valueStruct := reflect.ValueOf(v)
-	typeStruct := reflect.TypeOf(v)
-	if valueStruct.Kind() != reflect.Struct {
-		return ErrNotStruct
+func validateStringContains(str string, substr string) error {
+	if !strings.Contains(str, substr) {
+		return errRuleFailed("contains", substr, str)
 	}
+	return nil
+}
 
-	var errs ValidationErrors
-
-	for i := 0; i < valueStruct.NumField(); i++ {
-		valueField := valueStruct.Field(i)
-		typeField := typeStruct.Field(i)
+func validateStringPrefix(str string, prefix string) error {
+	if !strings.HasPrefix(str, prefix) {
+		return errRuleFailed("prefix", prefix, str)
+	}
+	return nil
+}
 
-		validateTag := typeField.Tag.Get("validate")
+func validateStringSuffix(str string, suffix string) error {
+	if !strings.HasSuffix(str, suffix) {
+		return errRuleFailed("suffix", suffix, str)
+	}
+	return nil
+}
 
-		if validateTag == "" {
+func validateIntIn(num int64, arg string) error {
+	allowed := splitList(arg)
+	for _, s := range allowed {
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
 			continue
 		}
+		if i == num {
+			return nil
+		}
+	}
+	return errRuleFailed("in", arg, num)
+}
 
-		if !typeField.IsExported() {
-			errs = append(errs, ValidationError{FieldName: valueField.Type().Name(), Err: ErrValidateForUnexportedFields})
+func validateIntNotIn(num int64, arg string) error {
+	if validateIntIn(num, arg) == nil {
+		return errRuleFailed("notin", arg, num)
+	}
+	return nil
+}
+
+func validateIntRange(num int64, arg string) error {
+	lo, hi, _ := parseRange(arg)
+	if validateIntMinMax(num, "min", lo) != nil || validateIntMinMax(num, "max", hi) != nil {
+		return errRuleFailed("range", arg, num)
+	}
+	return nil
+}
+
+func validateUintRange(num uint64, arg string) error {
+	lo, hi, _ := parseRange(arg)
+	if validateUintMinMax(num, "min", lo) != nil || validateUintMinMax(num, "max", hi) != nil {
+		return errRuleFailed("range", arg, num)
+	}
+	return nil
+}
+
+func validateUintIn(num uint64, arg string) error {
+	allowed := splitList(arg)
+	for _, s := range allowed {
+		i, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			// a negative candidate can never match an unsigned value
 			continue
 		}
+		if i == num {
+			return nil
+		}
+	}
+	return errRuleFailed("in", arg, num)
+}
 
-		if validateSyntax(validateTag) {
-			errs = append(errs, ValidationError{FieldName: valueField.Type().Name(), Err: ErrInvalidValidatorSyntax})
-			continue
+func validateUintNotIn(num uint64, arg string) error {
+	if validateUintIn(num, arg) == nil {
+		return errRuleFailed("notin", arg, num)
+	}
+	return nil
+}
+
+func isInteger(s string) bool {
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return true
+	}
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
+
+func isFloat(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// Rule is a single rule of a tag, e.g. Name "min" and Arg "3" for "min:3".
+// Target is "key" or "value" for a map rule prefixed with "key:" or
+// "value:", or their "key=" and "value=" spellings, and "" otherwise.
+type Rule struct {
+	Name   string
+	Arg    string
+	Target string
+}
+
+// ParseTag splits a tag such as "min:3;max:10" into its rules. It returns an
+// error wrapping ErrInvalidValidatorSyntax if any rule is malformed or a rule
+// name appears twice. Checks that depend on the field type, like integer-only
+// bounds or whether the field a "min:$Field" bound names exists, are not
+// applied.
+func ParseTag(tag string) ([]Rule, error) {
+	if tag == "" {
+		return nil, nil
+	}
+	var rules []Rule
+	var set ruleSet
+	for i, raw := range strings.Split(tag, ";") {
+		key, target, rule := cutTarget(strings.TrimSpace(raw))
+		name, arg, hasArg := parseRule(rule)
+		scope := ""
+		switch {
+		case key:
+			scope = "key"
+		case target:
+			scope = "value"
 		}
+		if !validRuleSyntax(name, arg, hasArg) || !set.add(name, scope) {
+			_, known := builtinRules[name]
+			return nil, errRuleSyntax(raw, i+1, known)
+		}
+		rules = append(rules, Rule{Name: name, Arg: arg, Target: scope})
+	}
+	return rules, nil
+}
 
-		for _, tags := range strings.Split(validateTag, ";") {
-			switch typeField.Type.Kind() {
-			case reflect.String:
-				if err := validateString(valueField.String(), tags); err != nil {
-					errs = append(errs, ValidationError{FieldName: valueField.Type().Name(), Err: err})
-				}
-			case reflect.Int:
-				if err := validateInt(int(valueField.Int()), tags); err != nil {
-					errs = append(errs, ValidationError{FieldName: valueField.Type().Name(), Err: err})
-				}
-			case reflect.Slice:
-				if valueField.Type().Elem().Kind() == reflect.Int {
-					for _, num := range valueField.Interface().([]int) {
-						if err := validateInt(num, tags); err != nil {
-							errs = append(errs, ValidationError{FieldName: valueField.Type().Name(), Err: err})
-						}
-					}
-				} else if valueField.Type().Elem().Kind() == reflect.String {
-					for _, str := range valueField.Interface().([]string) {
-						if err := validateString(str, tags); err != nil {
-							errs = append(errs, ValidationError{FieldName: valueField.Type().Name(), Err: err})
-						}
-					}
-				} else {
-					errs = append(errs, ValidationError{FieldName: valueField.Type().Name(), Err: ErrUnsupportedType})
-				}
-			default:
-				errs = append(errs, ValidationError{FieldName: valueField.Type().Name(), Err: ErrUnsupportedType})
+// ruleSet collects the rules of one tag to catch a repeated one. A rule may
+// appear once on each side of a dive, and apart from that once per scope:
+// "key" for the keys of a map, "size" for min and max bounding a length.
+type ruleSet struct {
+	seen  map[string]bool
+	dived bool
+}
+
+// add records the rule name in scope, "" for the usual one, and reports
+// whether it was new.
+func (s *ruleSet) add(name, scope string) bool {
+	if scope == "" && s.dived && name != "dive" {
+		scope = "dive"
+	}
+	s.dived = s.dived || name == "dive"
+	id := scope + ";" + name
+	if s.seen[id] {
+		return false
+	}
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	s.seen[id] = true
+	return true
+}
+
+// validRuleSyntax reports whether a single rule is well formed: its name is
+// known, it has an argument exactly when the rule takes one, and the argument
+// parses for that rule.
+func validRuleSyntax(name, arg string, hasArg bool) bool {
+	spec, ok := builtinRules[name]
+	if !ok || hasArg != spec.takesArg {
+		return false
+	}
+	switch name {
+	case "in", "notin", "contains", "prefix", "suffix", "eqfield", "nefield", "gtfield", "ltfield":
+		return len(arg) > 0
+	case "regexp":
+		_, err := compileRegexp(arg)
+		return err == nil
+	case "password":
+		_, err := parsePasswordSpec(arg)
+		return err == nil
+	case "oneof":
+		return len(strings.Fields(arg)) > 0
+	case "required_if":
+		return len(strings.Fields(arg)) == 2
+	case "eq":
+		return arg == "true" || arg == "false"
+	case "before", "after":
+		_, err := parseTime(arg)
+		return err == nil
+	case "min", "max":
+		// "$Field" bounds by a sibling field, which only a type can tell exists
+		return isInteger(arg) || isFloat(arg) || strings.HasPrefix(arg, "$") && token.IsIdentifier(arg[1:])
+	case "len", "minlen", "maxlen", "runemin", "runemax":
+		return isInteger(arg)
+	case "gt", "gte", "lt", "lte":
+		return len(arg) > 0
+	case "range":
+		lo, hi, ok := parseRange(arg)
+		return ok && isInteger(lo) && isInteger(hi) && rangeOrdered(lo, hi)
+	}
+	return true
+}
+
+// parseRange splits "lo-hi" into its bounds, either of which may be negative.
+func parseRange(arg string) (lo, hi string, ok bool) {
+	if len(arg) < 3 {
+		return "", "", false
+	}
+	i := strings.Index(arg[1:], "-")
+	if i < 0 {
+		return "", "", false
+	}
+	return arg[:i+1], arg[i+2:], true
+}
+
+func rangeOrdered(lo, hi string) bool {
+	loInt, loErr := strconv.ParseInt(lo, 10, 64)
+	hiInt, hiErr := strconv.ParseInt(hi, 10, 64)
+	switch {
+	case loErr == nil && hiErr == nil:
+		return loInt <= hiInt
+	case loErr == nil:
+		// hi is above math.MaxInt64
+		return true
+	case hiErr == nil:
+		return false
+	}
+	loUint, _ := strconv.ParseUint(lo, 10, 64)
+	hiUint, _ := strconv.ParseUint(hi, 10, 64)
+	return loUint <= hiUint
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// kindSet is a set of the kinds of field, grouped as the rules see them.
+type kindSet uint8
+
+const (
+	stringKinds kindSet = 1 << iota
+	intKinds            // signed and unsigned integers
+	floatKinds
+	boolKinds
+	complexKinds
+	timeKinds      // time.Time
+	structKinds    // structs other than time.Time
+	containerKinds // a slice, array or map as a whole, for bounds on its length
+	anyKind        = ^kindSet(0)
+)
+
+func kindSetOf(typ reflect.Type) kindSet {
+	switch kind := typ.Kind(); {
+	case kind == reflect.String:
+		return stringKinds
+	case isIntegerKind(kind):
+		return intKinds
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		return floatKinds
+	case kind == reflect.Bool:
+		return boolKinds
+	case kind == reflect.Complex64 || kind == reflect.Complex128:
+		return complexKinds
+	case typ == timeType:
+		return timeKinds
+	case kind == reflect.Struct:
+		return structKinds
+	case isContainerKind(kind):
+		return containerKinds
+	}
+	return 0
+}
+
+// ruleFitsKind reports whether rule can check a value of typ, which is the
+// container itself for a rule on its length. Kinds no rule supports, and
+// interfaces that are only known at run time, are left to fail with
+// ErrUnsupportedType when validated.
+func ruleFitsKind(rule Rule, typ reflect.Type) bool {
+	set := kindSetOf(typ)
+	if set == 0 {
+		return true
+	}
+	if builtinRules[rule.Name].kinds&set == 0 {
+		return false
+	}
+	switch rule.Name {
+	case "gt", "gte", "lt", "lte":
+		return set == stringKinds || isInteger(rule.Arg)
+	case "min", "max":
+		// a "$Field" bound is left to the struct, which knows the field
+		return isInteger(rule.Arg) || set == floatKinds && isFloat(rule.Arg)
+	case "in", "notin", "oneof":
+		candidates := splitList(rule.Arg)
+		if rule.Name == "oneof" {
+			candidates = strings.Fields(rule.Arg)
+		}
+		for _, candidate := range candidates {
+			if !candidateFits(candidate, set) {
+				return false
 			}
 		}
 	}
+	return true
+}
 
-	if len(errs) > 0 {
-		return errs
+func candidateFits(candidate string, set kindSet) bool {
+	switch set {
+	case intKinds:
+		return isInteger(candidate)
+	case floatKinds:
+		return isFloat(candidate)
+	case complexKinds:
+		_, err := strconv.ParseComplex(candidate, 128)
+		return err == nil
+	}
+	return true
+}
+
+// ruleFits reports whether the single rule raw is well formed and applies
+// to a value of typ.
+func ruleFits(raw string, typ reflect.Type) bool {
+	name, arg, hasArg := parseRule(raw)
+	return validRuleSyntax(name, arg, hasArg) && ruleFitsKind(Rule{Name: name, Arg: arg}, typ)
+}
+
+// min and max are inclusive bounds: a value equal to the bound passes, for
+// numbers as well as for lengths. gt and lt exclude the bound, gte and lte
+// are the inclusive forms spelled out; all four are for integers and, see
+// validateStringCompare, strings. A bound of 0 is a bound like any other:
+// min:0 rejects negative numbers and max:0 positive ones.
+func validateIntMinMax(num int64, name, arg string) error {
+	length, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		// bound above math.MaxInt64: every signed value is below it
+		if name == "min" {
+			return errRuleFailed(name, arg, num)
+		}
+		return nil
+	}
+	switch name {
+	case "min":
+		if num < length {
+			return errRuleFailed(name, arg, num)
+		}
+	case "max":
+		if num > length {
+			return errRuleFailed(name, arg, num)
+		}
 	}
 	return nil
 }
+
+func validateUintMinMax(num uint64, name, arg string) error {
+	length, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil {
+		// negative bound: every unsigned value is above it
+		if name == "max" {
+			return errRuleFailed(name, arg, num)
+		}
+		return nil
+	}
+	switch name {
+	case "min":
+		if num < length {
+			return errRuleFailed(name, arg, num)
+		}
+	case "max":
+		if num > length {
+			return errRuleFailed(name, arg, num)
+		}
+	}
+	return nil
+}
+
+// a bound above math.MaxInt64 is greater than every signed value
+func validateIntGt(num int64, arg string) error {
+	bound, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || num <= bound {
+		return errRuleFailed("gt", arg, num)
+	}
+	return nil
+}
+
+func validateIntGte(num int64, arg string) error {
+	bound, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || num < bound {
+		return errRuleFailed("gte", arg, num)
+	}
+	return nil
+}
+
+func validateIntLt(num int64, arg string) error {
+	bound, err := strconv.ParseInt(arg, 10, 64)
+	if err == nil && num >= bound {
+		return errRuleFailed("lt", arg, num)
+	}
+	return nil
+}
+
+func validateIntLte(num int64, arg string) error {
+	bound, err := strconv.ParseInt(arg, 10, 64)
+	if err == nil && num > bound {
+		return errRuleFailed("lte", arg, num)
+	}
+	return nil
+}
+
+// a negative bound is less than every unsigned value
+func validateUintGt(num uint64, arg string) error {
+	bound, err := strconv.ParseUint(arg, 10, 64)
+	if err == nil && num <= bound {
+		return errRuleFailed("gt", arg, num)
+	}
+	return nil
+}
+
+func validateUintGte(num uint64, arg string) error {
+	bound, err := strconv.ParseUint(arg, 10, 64)
+	if err == nil && num < bound {
+		return errRuleFailed("gte", arg, num)
+	}
+	return nil
+}
+
+func validateUintLt(num uint64, arg string) error {
+	bound, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil || num >= bound {
+		return errRuleFailed("lt", arg, num)
+	}
+	return nil
+}
+
+func validateUintLte(num uint64, arg string) error {
+	bound, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil || num > bound {
+		return errRuleFailed("lte", arg, num)
+	}
+	return nil
+}
+
+func validateFloatIn(num float64, arg string) error {
+	allowed := splitList(arg)
+	for _, s := range allowed {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			continue
+		}
+		if f == num {
+			return nil
+		}
+	}
+	return errRuleFailed("in", arg, num)
+}
+
+func validateFloatNotIn(num float64, arg string) error {
+	if validateFloatIn(num, arg) == nil {
+		return errRuleFailed("notin", arg, num)
+	}
+	return nil
+}
+
+// complex numbers have no order, so in, notin and oneof are their only
+// rules. Candidates are parsed at the precision of the field, bitSize.
+func validateComplexIn(num complex128, bitSize int, arg string) error {
+	for _, s := range splitList(arg) {
+		c, err := strconv.ParseComplex(s, bitSize)
+		if err == nil && c == num {
+			return nil
+		}
+	}
+	return errRuleFailed("in", arg, num)
+}
+
+func validateComplex(num complex128, bitSize int, name, arg string) error {
+	switch name {
+	case "in":
+		return validateComplexIn(num, bitSize, arg)
+	case "notin":
+		if validateComplexIn(num, bitSize, arg) == nil {
+			return errRuleFailed("notin", arg, num)
+		}
+	}
+	return nil
+}
+
+func validateFloatMinMax(num float64, name, arg string) error {
+	bound, _ := strconv.ParseFloat(arg, 64)
+	switch name {
+	case "min":
+		if num < bound {
+			return errRuleFailed(name, arg, num)
+		}
+	case "max":
+		if num > bound {
+			return errRuleFailed(name, arg, num)
+		}
+	}
+	return nil
+}
+
+func validateBool(b bool, name, arg string) error {
+	switch name {
+	case "eq":
+		if strconv.FormatBool(b) != arg {
+			return errRuleFailed("eq", arg, b)
+		}
+	}
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// isDefault reports whether value equals def parsed for its type. Kinds a
+// default cannot be written for never match.
+func isDefault(value reflect.Value, def string) bool {
+	if !value.IsValid() {
+		return false
+	}
+	if value.Type() == timeType {
+		t, err := parseTime(def)
+		return err == nil && value.Interface().(time.Time).Equal(t)
+	}
+	switch {
+	case value.Kind() == reflect.String:
+		return value.String() == def
+	case value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(def)
+		return err == nil && value.Bool() == b
+	case value.CanInt():
+		n, err := strconv.ParseInt(def, 10, 64)
+		return err == nil && value.Int() == n
+	case value.CanUint():
+		n, err := strconv.ParseUint(def, 10, 64)
+		return err == nil && value.Uint() == n
+	case value.CanFloat():
+		f, err := strconv.ParseFloat(def, 64)
+		return err == nil && value.Float() == f
+	}
+	return false
+}
+
+// parsesAs reports whether s reads as a value of kind the way isDefault
+// reads it.
+func parsesAs(kind reflect.Kind, s string) bool {
+	var err error
+	switch kind {
+	case reflect.String:
+	case reflect.Bool:
+		_, err = strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err = strconv.ParseInt(s, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err = strconv.ParseUint(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		_, err = strconv.ParseFloat(s, 64)
+	default:
+		return false
+	}
+	return err == nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+func validateTime(t time.Time, name, arg string) error {
+	switch name {
+	case "before", "after":
+		bound, _ := parseTime(arg)
+		if (name == "before" && !t.Before(bound)) || (name == "after" && !t.After(bound)) {
+			return errRuleFailed(name, arg, t.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// isFieldRule reports whether name compares a field with a sibling field.
+func isFieldRule(name string) bool {
+	switch name {
+	case "eqfield", "nefield", "gtfield", "ltfield":
+		return true
+	}
+	return false
+}
+
+func isOrderedType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return isIntegerKind(t.Kind()) || t == timeType
+}
+
+// compareValues orders two values of the same ordered type.
+func compareValues(a, b reflect.Value) int {
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+	}
+	var less, greater bool
+	switch {
+	case a.Kind() == reflect.String:
+		less, greater = a.String() < b.String(), a.String() > b.String()
+	case a.Kind() == reflect.Float32 || a.Kind() == reflect.Float64:
+		less, greater = a.Float() < b.Float(), a.Float() > b.Float()
+	case a.CanInt():
+		less, greater = a.Int() < b.Int(), a.Int() > b.Int()
+	default:
+		less, greater = a.Uint() < b.Uint(), a.Uint() > b.Uint()
+	}
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// validateField checks a cross-field rule; value and sibling have the same type.
+func validateField(value, sibling reflect.Value, name, arg string) error {
+	equal := func() bool {
+		// times in different locations can still be the same instant
+		if value.Type() == timeType {
+			return compareValues(value, sibling) == 0
+		}
+		return value.Equal(sibling)
+	}
+	// an interface field is comparable as a type, not always by what it holds
+	if name == "eqfield" || name == "nefield" {
+		for _, v := range []reflect.Value{value, sibling} {
+			if !v.Comparable() {
+				return fmt.Errorf("%w: %s", ErrUnsupportedType, v.Type())
+			}
+		}
+	}
+	var ok bool
+	switch name {
+	case "eqfield":
+		ok = equal()
+	case "nefield":
+		ok = !equal()
+	case "gtfield":
+		ok = compareValues(value, sibling) > 0
+	case "ltfield":
+		ok = compareValues(value, sibling) < 0
+	}
+	if !ok {
+		return errRuleFailed(name, arg, value.Interface())
+	}
+	return nil
+}
+
+func isContainerRule(name string) bool {
+	return name == "len" || name == "minlen" || name == "maxlen"
+}
+
+func isSizeRule(name string) bool {
+	return name == "min" || name == "max"
+}
+
+func validateContainerLen(length int, name, arg string) error {
+	bound, _ := strconv.Atoi(arg)
+	switch name {
+	case "len":
+		if length != bound {
+			return errLengthFailed(name, arg, length)
+		}
+	case "minlen", "min":
+		if length < bound {
+			return errLengthFailed(name, arg, length)
+		}
+	case "maxlen", "max":
+		if length > bound {
+			return errLengthFailed(name, arg, length)
+		}
+	}
+	return nil
+}
+
+func validateString(str string, name, arg string) error {
+	switch name {
+	case "in":
+		if err := validateStringIn(str, arg); err != nil {
+			return err
+		}
+	case "notin":
+		if err := validateStringNotIn(str, arg); err != nil {
+			return err
+		}
+	case "len":
+		if err := validateStringLen(str, arg); err != nil {
+			return err
+		}
+	case "regexp":
+		if err := validateStringRegexp(str, arg); err != nil {
+			return err
+		}
+	case "contains":
+		if err := validateStringContains(str, arg); err != nil {
+			return err
+		}
+	case "prefix":
+		if err := validateStringPrefix(str, arg); err != nil {
+			return err
+		}
+	case "suffix":
+		if err := validateStringSuffix(str, arg); err != nil {
+			return err
+		}
+	case "email":
+		if err := validateStringEmail(str); err != nil {
+			return err
+		}
+	case "url":
+		if err := validateStringURL(str); err != nil {
+			return err
+		}
+	case "alpha", "alphanumeric", "numeric":
+		if err := validateStringClass(str, name); err != nil {
+			return err
+		}
+	case "password":
+		if err := validateStringPassword(str, arg); err != nil {
+			return err
+		}
+	case "uuid":
+		if err := validateStringUUID(str); err != nil {
+			return err
+		}
+	case "min", "max":
+		if err := validateStringMinMax(str, name, arg); err != nil {
+			return err
+		}
+	case "runemin", "runemax":
+		if err := validateStringRuneMinMax(str, name, arg); err != nil {
+			return err
+		}
+	case "gt", "gte", "lt", "lte":
+		if err := validateStringCompare(str, name, arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateInt(num int64, name, arg string) error {
+	switch name {
+	case "in":
+		if err := validateIntIn(num, arg); err != nil {
+			return err
+		}
+	case "notin":
+		if err := validateIntNotIn(num, arg); err != nil {
+			return err
+		}
+	case "min", "max":
+		if err := validateIntMinMax(num, name, arg); err != nil {
+			return err
+		}
+	case "range":
+		if err := validateIntRange(num, arg); err != nil {
+			return err
+		}
+	case "gt":
+		if err := validateIntGt(num, arg); err != nil {
+			return err
+		}
+	case "gte":
+		if err := validateIntGte(num, arg); err != nil {
+			return err
+		}
+	case "lt":
+		if err := validateIntLt(num, arg); err != nil {
+			return err
+		}
+	case "lte":
+		if err := validateIntLte(num, arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateUint(num uint64, name, arg string) error {
+	switch name {
+	case "in":
+		if err := validateUintIn(num, arg); err != nil {
+			return err
+		}
+	case "notin":
+		if err := validateUintNotIn(num, arg); err != nil {
+			return err
+		}
+	case "min", "max":
+		if err := validateUintMinMax(num, name, arg); err != nil {
+			return err
+		}
+	case "range":
+		if err := validateUintRange(num, arg); err != nil {
+			return err
+		}
+	case "gt":
+		if err := validateUintGt(num, arg); err != nil {
+			return err
+		}
+	case "gte":
+		if err := validateUintGte(num, arg); err != nil {
+			return err
+		}
+	case "lt":
+		if err := validateUintLt(num, arg); err != nil {
+			return err
+		}
+	case "lte":
+		if err := validateUintLte(num, arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateFloat(num float64, name, arg string) error {
+	switch name {
+	case "in":
+		if err := validateFloatIn(num, arg); err != nil {
+			return err
+		}
+	case "notin":
+		if err := validateFloatNotIn(num, arg); err != nil {
+			return err
+		}
+	case "min", "max":
+		if err := validateFloatMinMax(num, name, arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isScalarKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+func isContainerKind(kind reflect.Kind) bool {
+	return kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map
+}
+
+func isEmpty(value reflect.Value) bool {
+	if !value.IsValid() || value.IsZero() {
+		return true
+	}
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return false
+}
+
+// countNilElems returns how many elements of a slice or array of pointers are nil.
+func countNilElems(v reflect.Value) int {
+	if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() != reflect.Ptr {
+		return 0
+	}
+	n := 0
+	for i := 0; i < v.Len(); i++ {
+		if v.Index(i).IsNil() {
+			n++
+		}
+	}
+	return n
+}
+
+func isValueType(t reflect.Type) bool {
+	return isScalarKind(t.Kind()) || t == timeType
+}
+
+// sortedMapKeys returns the keys of m in a stable order so that errors for
+// map entries are reported deterministically.
+func sortedMapKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch a.Kind() {
+		case reflect.String:
+			return a.String() < b.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return a.Uint() < b.Uint()
+		case reflect.Float32, reflect.Float64:
+			return a.Float() < b.Float()
+		}
+		return fmt.Sprint(a) < fmt.Sprint(b)
+	})
+	return keys
+}
+
+// oneofList turns the space separated options of oneof into the argument of
+// the equivalent in rule.
+func oneofList(arg string) string {
+	options := strings.Fields(arg)
+	for i, option := range options {
+		options[i] = strings.NewReplacer(`\`, `\\`, ",", `\,`).Replace(option)
+	}
+	return strings.Join(options, ",")
+}
+
+func validateValue(value reflect.Value, name, arg string) error {
+	// oneof is in with options separated by spaces instead of commas
+	if name == "oneof" {
+		err := validateValue(value, "in", oneofList(arg))
+		var ruleErr *RuleError
+		if errors.As(err, &ruleErr) {
+			ruleErr.Rule, ruleErr.Arg = name, arg
+		}
+		return err
+	}
+	if value.Type() == timeType {
+		if !value.CanInterface() {
+			return fmt.Errorf("%w: unexported %s", ErrUnsupportedType, value.Type())
+		}
+		return validateTime(value.Interface().(time.Time), name, arg)
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return validateString(value.String(), name, arg)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return validateInt(value.Int(), name, arg)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return validateUint(value.Uint(), name, arg)
+	case reflect.Float32, reflect.Float64:
+		return validateFloat(value.Float(), name, arg)
+	case reflect.Bool:
+		return validateBool(value.Bool(), name, arg)
+	case reflect.Complex64:
+		return validateComplex(value.Complex(), 64, name, arg)
+	case reflect.Complex128:
+		return validateComplex(value.Complex(), 128, name, arg)
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedType, value.Type())
+}
+
+// dynamicRule wraps the built-in fn for a field of interface type, failing
+// with ErrUnsupportedType for a value the rule does not apply to, as the tag
+// of a field of that type would have been reported.
+func dynamicRule(name string, fn RuleFunc) RuleFunc {
+	return func(value reflect.Value, arg string) error {
+		if !ruleFitsKind(Rule{Name: name, Arg: arg}, value.Type()) {
+			return fmt.Errorf("%w: %s on %s", ErrUnsupportedType, name, value.Type())
+		}
+		return fn(value, arg)
+	}
+}
+
+func prefixErrors(prefix string, errs ValidationErrors) ValidationErrors {
+	for i := range errs {
+		errs[i].FieldName = prefix + "." + errs[i].FieldName
+	}
+	return errs
+}
+
+const defaultTagName = "validate"
+
+// RuleFunc validates a single value against the argument of its rule, e.g.
+// "10" for "min:10". Slice fields are checked element by element.
+type RuleFunc func(value reflect.Value, arg string) error
+
+// Validator holds the set of rules a tag may reference. The zero value is
+// ready to use and knows the built-in rules, like the one New returns.
+type Validator struct {
+	mu      sync.RWMutex
+	rules   map[string]registeredRule
+	message MessageFunc
+	// aliases maps deprecated rule names to the rule they stand for, and
+	// deprecated records the ones met in tags
+	aliases    map[string]string
+	deprecated map[string]bool
+	cache      sync.Map // structKey -> *structMeta
+}
+
+// MessageFunc returns the message to show for ve, for instance a
+// translation of ve.Err, or "" to keep the default one. ve.Message holds
+// the validate_msg message, if any.
+type MessageFunc func(ve ValidationError) string
+
+type registeredRule struct {
+	fn      RuleFunc
+	builtin bool
+}
+
+type ruleSpec struct {
+	// perValue rules are checked by validateValue, the others by the walker itself
+	perValue bool
+	takesArg bool
+	// kinds are the kinds of field the rule applies to, see ruleFitsKind
+	kinds kindSet
+}
+
+// builtinRules lists every rule a tag may use without registering it.
+var builtinRules = map[string]ruleSpec{
+	"required":     {kinds: anyKind},
+	"omitempty":    {kinds: anyKind},
+	"minlen":       {takesArg: true, kinds: containerKinds},
+	"maxlen":       {takesArg: true, kinds: containerKinds},
+	"in":           {perValue: true, takesArg: true, kinds: stringKinds | intKinds | floatKinds | complexKinds},
+	"notin":        {perValue: true, takesArg: true, kinds: stringKinds | intKinds | floatKinds | complexKinds},
+	"len":          {perValue: true, takesArg: true, kinds: stringKinds | containerKinds},
+	"min":          {perValue: true, takesArg: true, kinds: stringKinds | intKinds | floatKinds | containerKinds},
+	"max":          {perValue: true, takesArg: true, kinds: stringKinds | intKinds | floatKinds | containerKinds},
+	"range":        {perValue: true, takesArg: true, kinds: intKinds},
+	"gt":           {perValue: true, takesArg: true, kinds: stringKinds | intKinds},
+	"gte":          {perValue: true, takesArg: true, kinds: stringKinds | intKinds},
+	"lt":           {perValue: true, takesArg: true, kinds: stringKinds | intKinds},
+	"lte":          {perValue: true, takesArg: true, kinds: stringKinds | intKinds},
+	"runemin":      {perValue: true, takesArg: true, kinds: stringKinds},
+	"runemax":      {perValue: true, takesArg: true, kinds: stringKinds},
+	"regexp":       {perValue: true, takesArg: true, kinds: stringKinds},
+	"contains":     {perValue: true, takesArg: true, kinds: stringKinds},
+	"prefix":       {perValue: true, takesArg: true, kinds: stringKinds},
+	"suffix":       {perValue: true, takesArg: true, kinds: stringKinds},
+	"eq":           {perValue: true, takesArg: true, kinds: boolKinds},
+	"before":       {perValue: true, takesArg: true, kinds: timeKinds},
+	"after":        {perValue: true, takesArg: true, kinds: timeKinds},
+	"email":        {perValue: true, kinds: stringKinds},
+	"eqfield":      {takesArg: true, kinds: anyKind},
+	"nefield":      {takesArg: true, kinds: anyKind},
+	"gtfield":      {takesArg: true, kinds: anyKind},
+	"ltfield":      {takesArg: true, kinds: anyKind},
+	"url":          {perValue: true, kinds: stringKinds},
+	"alpha":        {perValue: true, kinds: stringKinds},
+	"alphanumeric": {perValue: true, kinds: stringKinds},
+	"numeric":      {perValue: true, kinds: stringKinds},
+	"password":     {perValue: true, takesArg: true, kinds: stringKinds},
+	"uuid":         {perValue: true, kinds: stringKinds},
+	"oneof":        {perValue: true, takesArg: true, kinds: stringKinds | intKinds | floatKinds | complexKinds},
+	// trim checks the rules after it against the string with leading and
+	// trailing white space removed; the field itself is not modified
+	"trim": {kinds: stringKinds},
+	// dive splits the rules of a slice, array or map field: those before it
+	// bound the number of elements, those after it apply to every element;
+	// required and omitempty always apply to the field itself
+	"dive": {kinds: anyKind},
+	// required_if:Field value requires the field when its sibling Field
+	// holds value
+	"required_if": {takesArg: true, kinds: anyKind},
+}
+
+// SupportedRules returns the names of the built-in rules in sorted order.
+// Rules added with Register are not included.
+func SupportedRules() []string {
+	names := make([]string, 0, len(builtinRules))
+	for name := range builtinRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SupportedKinds returns the kinds of field the built-in rules apply to, in
+// reflect.Kind order. Pointers to them are dereferenced first, and time.Time
+// fields additionally take before and after.
+func SupportedKinds() []reflect.Kind {
+	var kinds []reflect.Kind
+	for kind := reflect.Invalid; kind <= reflect.UnsafePointer; kind++ {
+		if isScalarKind(kind) || isContainerKind(kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// builtinFuncs are the RuleFuncs of the perValue builtins, which a
+// Validator falls back to for the names not registered with it.
+var builtinFuncs = func() map[string]registeredRule {
+	funcs := make(map[string]registeredRule, len(builtinRules))
+	for name, spec := range builtinRules {
+		if !spec.perValue {
+			continue
+		}
+		name := name
+		funcs[name] = registeredRule{
+			fn: func(value reflect.Value, arg string) error {
+				return validateValue(value, name, arg)
+			},
+			builtin: true,
+		}
+	}
+	return funcs
+}()
+
+var defaultValidator = New()
+
+// New returns a Validator knowing the built-in rules; it is the same as a
+// zero Validator.
+func New() *Validator {
+	return &Validator{}
+}
+
+// Register adds a rule under name, replacing any rule already registered
+// with that name. The argument of a registered rule is passed through as
+// is, and the rule may be used without one.
+//
+// Built-in rules checked value by value, such as min or email, may be
+// replaced too; len, min and max still bound the number of elements of a
+// container, as they do before a dive. Register panics if name is one of
+// the rules the walker applies itself: required, omitempty, minlen,
+// maxlen, trim, dive, required_if, eqfield, nefield, gtfield and ltfield.
+func (v *Validator) Register(name string, fn RuleFunc) {
+	if spec, ok := builtinRules[name]; ok && !spec.perValue {
+		panic(fmt.Sprintf("validator: built-in rule %q cannot be replaced", name))
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.rules == nil {
+		v.rules = make(map[string]registeredRule)
+	}
+	v.rules[name] = registeredRule{fn: fn}
+	v.cache.Range(func(key, _ any) bool {
+		v.cache.Delete(key)
+		return true
+	})
+}
+
+// Deprecate makes v accept alias, such as "min_len", in tags as an old
+// spelling of the rule name, such as "minlen". Validation results are the
+// same either way; the aliases met are listed by Deprecations.
+func (v *Validator) Deprecate(alias, name string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.aliases == nil {
+		v.aliases = make(map[string]string)
+	}
+	v.aliases[alias] = name
+	v.cache.Range(func(key, _ any) bool {
+		v.cache.Delete(key)
+		return true
+	})
+}
+
+// Deprecations returns, sorted, the deprecated aliases v has met in the tags
+// of the types it validated so far.
+func (v *Validator) Deprecations() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	var aliases []string
+	for alias := range v.deprecated {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// resolveAlias returns the rule name stands for, recording name if it is a
+// deprecated alias.
+func (v *Validator) resolveAlias(name string) string {
+	v.mu.RLock()
+	canonical, ok := v.aliases[name]
+	v.mu.RUnlock()
+	if !ok {
+		return name
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.deprecated == nil {
+		v.deprecated = make(map[string]bool)
+	}
+	v.deprecated[name] = true
+	return canonical
+}
+
+// SetMessageFunc makes v pass every error it reports through fn, which sets
+// its Message. A nil fn restores the default messages.
+func (v *Validator) SetMessageFunc(fn MessageFunc) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.message = fn
+}
+
+func (v *Validator) messageFunc() MessageFunc {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.message
+}
+
+func (v *Validator) rule(name string) (registeredRule, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	r, ok := v.rules[name]
+	if !ok {
+		r, ok = builtinFuncs[name]
+	}
+	return r, ok
+}
+
+func (v *Validator) ruleSyntaxValid(rule string, typ reflect.Type) bool {
+	name, _, _ := parseRule(rule)
+	if r, ok := v.rule(name); ok && !r.builtin {
+		return true
+	}
+	return ruleFits(rule, typ)
+}
+
+// Validate checks every field of x against the rules known to v.
+func (v *Validator) Validate(x any) error {
+	return validate(reflect.ValueOf(x), &walker{v: v, tagName: defaultTagName})
+}
+
+type walker struct {
+	ctx       context.Context
+	v         *Validator
+	tagName   string
+	firstOnly bool
+	// shallow skips nested structs, including those held by interfaces and containers
+	shallow bool
+	// skipDefaults skips the rules of fields still holding their default tag
+	skipDefaults bool
+	// jsonNames names fields after their json tag
+	jsonNames bool
+	// only restricts the outermost struct to the fields named in it
+	only map[string]bool
+	// maxDepth bounds depth, the number of structs being validated, with 0
+	// or less meaning DefaultMaxDepth
+	maxDepth int
+	depth    int
+	// onPath holds the addressable structs being validated, to stop at
+	// pointer cycles
+	onPath map[structAddr]bool
+	// clean applies modifiers such as trim to the copy made by
+	// ValidateAndClean; owned tells whether the struct being validated is
+	// part of that copy, and copies maps the pointers and slices copied so
+	// far to their copy
+	clean  bool
+	owned  bool
+	copies map[structAddr]reflect.Value
+	// err is set when ctx is done or maxDepth is exceeded and the walk was aborted
+	err error
+}
+
+type structAddr struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+func (w *walker) stop(errs ValidationErrors) bool {
+	return w.firstOnly && len(errs) > 0
+}
+
+// Validate checks every field of v and returns all failures as ValidationErrors.
+//
+// Errors come in field declaration order, and in tag order for the rules of
+// one field. Fields promoted from an embedded struct follow the embedded
+// field itself. The errors of a nested struct take the place of the field
+// holding it, ahead of that field's own rules; slice elements keep their
+// index order and map entries are sorted by key.
+func Validate(v any) error {
+	return ValidateValue(reflect.ValueOf(v))
+}
+
+// ValidateStruct is Validate under a name that says what v must be. Any v
+// that is not a struct or a non-nil pointer to one, such as nil, a map or a
+// func, fails with ErrNotStruct.
+func ValidateStruct(v any) error {
+	return Validate(v)
+}
+
+// ValidateValue is Validate for callers already holding a reflect.Value,
+// such as frameworks walking their own types. rv may hold the struct
+// directly or through an interface or a pointer.
+func ValidateValue(rv reflect.Value) error {
+	return validate(rv, &walker{v: defaultValidator, tagName: defaultTagName})
+}
+
+// ValidateAndClean validates a copy of v and applies the modifiers, such as
+// trim, to it. It returns that copy, a struct or a pointer to one like v,
+// along with the ValidationErrors of the cleaned values; v itself is left as
+// it is, and only fields with a modifier differ. Pointers and slices leading
+// to nested structs are copied too. Structs held by maps or interfaces and
+// fields promoted through embedded pointers are validated but not cleaned.
+func ValidateAndClean(v any) (any, error) {
+	valueStruct, err := structValue(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	clean := reflect.New(valueStruct.Type())
+	clean.Elem().Set(valueStruct)
+	w := &walker{v: defaultValidator, tagName: defaultTagName, clean: true, owned: true}
+	if valueStruct.CanAddr() {
+		// a pointer back to v leads to the copy
+		w.copies = map[structAddr]reflect.Value{{ptr: valueStruct.Addr().Pointer(), typ: clean.Type()}: clean}
+	}
+	err = validate(clean, w)
+	var errs ValidationErrors
+	if err != nil && !errors.As(err, &errs) {
+		return nil, err
+	}
+	if reflect.ValueOf(v).Kind() == reflect.Ptr {
+		return clean.Interface(), err
+	}
+	return clean.Elem().Interface(), err
+}
+
+// ValidateSimple is like Validate but flattens ValidationErrors into a plain
+// error holding the combined message, one failure per line, for callers
+// that only check err != nil. Other errors, such as ErrNotStruct, are
+// returned as they are.
+func ValidateSimple(v any) error {
+	err := Validate(v)
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		return errors.New(strings.TrimSuffix(errs.Error(), "\n"))
+	}
+	return err
+}
+
+// MustValidate is like Validate but panics if v is invalid. The panic value
+// is the error Validate returned, usually ValidationErrors.
+func MustValidate(v any) {
+	if err := Validate(v); err != nil {
+		panic(err)
+	}
+}
+
+// ValidateContext is like Validate but checks ctx between fields and aborts
+// with ctx.Err() once ctx is cancelled or its deadline passes.
+func ValidateContext(ctx context.Context, v any) error {
+	return validate(reflect.ValueOf(v), &walker{ctx: ctx, v: defaultValidator, tagName: defaultTagName})
+}
+
+// Options tune how ValidateWith walks a value. Tag syntax is not among
+// them: the tags of a type are checked once, on first use, and cached, and
+// CheckTags can do that at startup.
+type Options struct {
+	// Deep makes validation descend into nested structs, pointers to structs
+	// and structs held by interface fields, slices, arrays and maps. Validate
+	// always validates deeply; without Deep only the fields of v itself are
+	// checked.
+	Deep bool
+	// SkipDefaults treats a field holding the value of its `default:"..."`
+	// tag, as used by defaulting libraries, as not set: its rules are
+	// skipped, except required. A pointer field is compared by the value it
+	// points to.
+	SkipDefaults bool
+	// JSONNames reports fields by the name of their json tag, so that errors
+	// match what a client sent: `json:"user_name,omitempty"` is reported as
+	// "user_name". Fields without a json name, or tagged `json:"-"`, keep
+	// their Go name.
+	JSONNames bool
+	// MaxDepth bounds how many levels of nested structs are descended into,
+	// DefaultMaxDepth if 0 or negative. Going deeper aborts validation with an error
+	// wrapping ErrMaxDepth. Pointer cycles are never followed around.
+	MaxDepth int
+}
+
+func (o Options) walker(v *Validator) walker {
+	return walker{v: v, tagName: defaultTagName, shallow: !o.Deep, skipDefaults: o.SkipDefaults, jsonNames: o.JSONNames, maxDepth: o.MaxDepth}
+}
+
+// ValidateWith is like Validate but configured by opts.
+func ValidateWith(v any, opts Options) error {
+	w := opts.walker(defaultValidator)
+	return validate(reflect.ValueOf(v), &w)
+}
+
+// BatchValidator validates many values with the same Validator and options,
+// such as the records of a bulk import. It is safe for concurrent use.
+// Each call costs what ValidateWith costs: the rules of a type are parsed on
+// first use and cached by the Validator, whichever way it is called.
+type BatchValidator struct {
+	template walker
+}
+
+// NewBatch returns a BatchValidator applying opts, and the rules and
+// message func of v, to every value.
+func (v *Validator) NewBatch(opts Options) *BatchValidator {
+	return &BatchValidator{template: opts.walker(v)}
+}
+
+// NewBatchValidator is like NewBatch with the built-in rules only, as used
+// by the package-level functions.
+func NewBatchValidator(opts Options) *BatchValidator {
+	return defaultValidator.NewBatch(opts)
+}
+
+// Validate is like ValidateWith with the Validator and options of b.
+func (b *BatchValidator) Validate(v any) error {
+	w := b.template
+	return validate(reflect.ValueOf(v), &w)
+}
+
+// ValidateWithTag is like Validate but reads rules from the tagName struct tag
+// instead of "validate". An empty tagName falls back to "validate".
+func ValidateWithTag(v any, tagName string) error {
+	if tagName == "" {
+		tagName = defaultTagName
+	}
+	return validate(reflect.ValueOf(v), &walker{v: defaultValidator, tagName: tagName})
+}
+
+// ValidateFirst is like Validate but returns as soon as the first field fails,
+// skipping the remaining fields and rules. The result is a ValidationErrors
+// holding exactly that failure. Prefer it on hot paths that only need to
+// reject the input, since the cost grows only up to the first failure.
+func ValidateFirst(v any) error {
+	return validate(reflect.ValueOf(v), &walker{v: defaultValidator, tagName: defaultTagName, firstOnly: true})
+}
+
+// ValidateSlice validates each element of a slice or array of structs, or of
+// pointers to structs. Field names are prefixed with the element index, as in
+// "[2].Email"; an element that cannot be validated at all, such as a nil
+// pointer, is reported under its index alone.
+func ValidateSlice(v any) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return fmt.Errorf("%w: got %s", ErrNotSlice, value.Kind())
+	}
+
+	var errs ValidationErrors
+	for i := 0; i < value.Len(); i++ {
+		index := fmt.Sprintf("[%d]", i)
+		err := validate(value.Index(i), &walker{v: defaultValidator, tagName: defaultTagName})
+		var elemErrs ValidationErrors
+		switch {
+		case err == nil:
+		case errors.As(err, &elemErrs):
+			errs = append(errs, prefixErrors(index, elemErrs)...)
+		default:
+			errs = append(errs, ValidationError{FieldName: index, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateFields is like Validate but only checks the named fields of v,
+// e.g. those present in a partial update. The fields of a named nested
+// struct are all checked. A name v has no field for fails with
+// ErrUnknownField.
+func ValidateFields(v any, fields ...string) error {
+	only := make(map[string]bool, len(fields))
+	for _, name := range fields {
+		only[name] = true
+	}
+	return validate(reflect.ValueOf(v), &walker{v: defaultValidator, tagName: defaultTagName, only: only})
+}
+
+// CheckTags reports every malformed rule in the tags of the struct type of
+// v and of the structs nested in it, as ValidationErrors wrapping
+// ErrInvalidValidatorSyntax. Only the type of v is looked at, so a zero value
+// or a typed nil pointer such as (*Request)(nil) will do. Structs held by
+// slices, arrays and maps are checked too, under names like "Items[].Name".
+// Tags are checked once per type and the result is cached, so calling it at
+// startup also takes that cost off the first Validate.
+func CheckTags(v any) error {
+	return defaultValidator.CheckTags(v)
+}
+
+// CheckTags is like the package-level CheckTags, knowing the rules
+// registered with v and the aliases it deprecated.
+func (v *Validator) CheckTags(x any) error {
+	typ := reflect.TypeOf(x)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: got %v", ErrNotStruct, typ)
+	}
+	if errs := checkTags(v, typ, defaultTagName, map[reflect.Type]bool{}); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func checkTags(v *Validator, typ reflect.Type, tagName string, seen map[reflect.Type]bool) ValidationErrors {
+	// seen holds the types being checked, to stop at self-referencing ones
+	if seen[typ] {
+		return nil
+	}
+	seen[typ] = true
+	defer delete(seen, typ)
+
+	var errs ValidationErrors
+	for _, field := range v.structMeta(typ, tagName).fields {
+		if !field.exported {
+			continue
+		}
+		fieldType := typ.FieldByIndex(field.index).Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.nested {
+			errs = append(errs, prefixErrors(field.name, checkTags(v, fieldType, tagName, seen))...)
+		} else if elemType, ok := structElem(fieldType); ok {
+			errs = append(errs, prefixErrors(field.name+"[]", checkTags(v, elemType, tagName, seen))...)
+		}
+		for _, rule := range field.rules {
+			if rule.invalid {
+				errs = append(errs, ValidationError{FieldName: field.name, Err: rule.syntaxErr})
+			}
+		}
+	}
+	return errs
+}
+
+// ValidateMutuallyExclusive checks that exactly one of the named fields of
+// the struct v is set, i.e. not the zero value. It fails with a single
+// ValidationError naming all the fields, such as "Email,Phone", when none or
+// more than one is set, with ErrUnknownField for a name v does not have and
+// with ErrValidateForUnexportedFields for an unexported one. A field promoted
+// through a nil embedded pointer is not set.
+func ValidateMutuallyExclusive(v any, fields ...string) error {
+	valueStruct, err := structValue(reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+
+	set := 0
+	for _, name := range fields {
+		typeField, ok := valueStruct.Type().FieldByName(name)
+		if !ok {
+			return fmt.Errorf("%w: %q in %s", ErrUnknownField, name, valueStruct.Type())
+		}
+		if !typeField.IsExported() {
+			return fmt.Errorf("%w: %q in %s", ErrValidateForUnexportedFields, name, valueStruct.Type())
+		}
+		if field, err := valueStruct.FieldByIndexErr(typeField.Index); err == nil && !field.IsZero() {
+			set++
+		}
+	}
+	if set != 1 {
+		return ValidationErrors{{
+			FieldName: strings.Join(fields, ","),
+			Err:       fmt.Errorf("%w: exactly one of %s must be set, got %d", ErrInvalidatedField, strings.Join(fields, ", "), set),
+		}}
+	}
+	return nil
+}
+
+// ValidateToMap runs Validate and groups the error messages by field name.
+// Fields that passed are absent from the map. If v cannot be validated at
+// all, e.g. because it is not a struct, the error is keyed by "".
+func ValidateToMap(v any) map[string][]string {
+	err := Validate(v)
+	if err == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		return map[string][]string{"": {err.Error()}}
+	}
+	byField := make(map[string][]string)
+	for _, e := range errs {
+		byField[e.FieldName] = append(byField[e.FieldName], e.message())
+	}
+	return byField
+}
+
+func validate(value reflect.Value, w *walker) error {
+	if w.ctx == nil {
+		w.ctx = context.Background()
+	}
+	valueStruct, err := structValue(value)
+	if err != nil {
+		return err
+	}
+
+	errs := w.validateStruct(valueStruct)
+	if w.err != nil {
+		return w.err
+	}
+	if message := w.v.messageFunc(); message != nil {
+		for i := range errs {
+			if msg := message(errs[i]); msg != "" {
+				errs[i].Message = msg
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// structValue returns the struct value holds, directly or through an
+// interface or a pointer. Anything else, nil included, fails with
+// ErrNotStruct.
+func structValue(value reflect.Value) (reflect.Value, error) {
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	switch {
+	case !value.IsValid():
+		return value, fmt.Errorf("%w: got nil", ErrNotStruct)
+	case value.Kind() == reflect.Ptr && value.IsNil():
+		return value, fmt.Errorf("%w: got nil pointer", ErrNotStruct)
+	case value.Kind() == reflect.Ptr:
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return value, fmt.Errorf("%w: got %s", ErrNotStruct, value.Kind())
+	}
+	return value, nil
+}
+
+// validateElems validates the structs held by the slice, array or map
+// container, naming their fields like "Addresses[0].Zip". Nil elements are
+// skipped.
+func (w *walker) validateElems(name string, container reflect.Value, owned bool) ValidationErrors {
+	if container.Kind() == reflect.Ptr {
+		if container.IsNil() {
+			return nil
+		}
+		container, owned = w.own(container, owned)
+	}
+	elem := func(value reflect.Value, owned bool) (reflect.Value, bool, bool) {
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return value, false, false
+			}
+			value, owned = w.own(value, owned)
+		}
+		return value, owned, true
+	}
+
+	var errs ValidationErrors
+	if container.Kind() == reflect.Map {
+		// map values cannot be set in place, so they are never cleaned
+		for _, key := range sortedMapKeys(container) {
+			if w.stop(errs) {
+				break
+			}
+			if value, _, ok := elem(container.MapIndex(key), false); ok {
+				errs = append(errs, prefixErrors(fmt.Sprintf("%s[%v]", name, key), w.validateOwned(false, value))...)
+			}
+		}
+		return errs
+	}
+	if container.Kind() == reflect.Slice {
+		container, owned = w.own(container, owned)
+	}
+	for i := 0; i < container.Len() && !w.stop(errs); i++ {
+		if value, owned, ok := elem(container.Index(i), owned); ok {
+			errs = append(errs, prefixErrors(fmt.Sprintf("%s[%d]", name, i), w.validateOwned(owned, value))...)
+		}
+	}
+	return errs
+}
+
+// validateOwned validates valueStruct, which is part of the copy being
+// cleaned if owned.
+func (w *walker) validateOwned(owned bool, valueStruct reflect.Value) ValidationErrors {
+	defer func(saved bool) { w.owned = saved }(w.owned)
+	w.owned = owned
+	return w.validateStruct(valueStruct)
+}
+
+// own keeps ValidateAndClean from cleaning the caller's value. ref is a
+// pointer or slice in the copy being cleaned if owned; own points it at a
+// copy of what it refers to, so that may be cleaned too, and returns ref,
+// dereferenced if a pointer. Copies are reused, so pointer cycles stay cycles.
+func (w *walker) own(ref reflect.Value, owned bool) (reflect.Value, bool) {
+	if !w.clean || !owned || !ref.CanSet() || ref.IsNil() {
+		if ref.Kind() == reflect.Ptr {
+			return ref.Elem(), false
+		}
+		return ref, false
+	}
+	key := structAddr{ptr: ref.Pointer(), typ: ref.Type()}
+	c, ok := w.copies[key]
+	if !ok || c.Kind() == reflect.Slice && c.Len() != ref.Len() {
+		if ref.Kind() == reflect.Ptr {
+			c = reflect.New(ref.Type().Elem())
+			c.Elem().Set(ref.Elem())
+		} else {
+			c = reflect.MakeSlice(ref.Type(), ref.Len(), ref.Len())
+			reflect.Copy(c, ref)
+		}
+		if w.copies == nil {
+			w.copies = make(map[structAddr]reflect.Value)
+		}
+		w.copies[key] = c
+	}
+	ref.Set(c)
+	if ref.Kind() == reflect.Ptr {
+		return ref.Elem(), true
+	}
+	return ref, true
+}
+
+// setCleaned stores the cleaned value in field, through a new pointer if
+// field is one so the caller's value stays as it is.
+func setCleaned(field, cleaned reflect.Value) {
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		ptr.Elem().Set(cleaned)
+		field.Set(ptr)
+		return
+	}
+	field.Set(cleaned)
+}
+
+func (w *walker) validateStruct(valueStruct reflect.Value) ValidationErrors {
+	meta := w.v.structMeta(valueStruct.Type(), w.tagName)
+
+	// nested structs are validated in full
+	only := w.only
+	w.only = nil
+	for name := range only {
+		if !meta.hasField(name) {
+			w.err = fmt.Errorf("%w: %q in %s", ErrUnknownField, name, valueStruct.Type())
+			return nil
+		}
+	}
+
+	if !meta.validatable {
+		return nil
+	}
+
+	maxDepth := w.maxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	if w.depth >= maxDepth {
+		w.err = fmt.Errorf("%w: more than %d levels at %s", ErrMaxDepth, maxDepth, valueStruct.Type())
+		return nil
+	}
+	w.depth++
+	defer func() { w.depth-- }()
+	if valueStruct.CanAddr() {
+		addr := structAddr{ptr: valueStruct.Addr().Pointer(), typ: valueStruct.Type()}
+		if w.onPath[addr] {
+			return nil
+		}
+		if w.onPath == nil {
+			w.onPath = make(map[structAddr]bool)
+		}
+		w.onPath[addr] = true
+		defer delete(w.onPath, addr)
+	}
+
+	var errs ValidationErrors
+
+	for _, field := range meta.fields {
+		if w.stop(errs) || w.err != nil {
+			break
+		}
+		if only != nil && !only[field.name] {
+			continue
+		}
+		if err := w.ctx.Err(); err != nil {
+			w.err = err
+			break
+		}
+		name := field.name
+		if w.jsonNames {
+			name = field.jsonName
+		}
+
+		// unexported fields are never read or recursed into, since reflection
+		// cannot access them safely; a tag on one is reported instead
+		if !field.exported {
+			if field.tagged {
+				errs = append(errs, ValidationError{FieldName: name, Err: ErrValidateForUnexportedFields})
+			}
+			continue
+		}
+
+		// a nil embedded pointer leaves its promoted fields unset
+		valueField, _ := valueStruct.FieldByIndexErr(field.index)
+		// values reached through an unexported field cannot be read through
+		// Interface; promoted() keeps them out, this guards the rule funcs
+		if valueField.IsValid() && !valueField.CanInterface() {
+			continue
+		}
+
+		// a field promoted through an embedded pointer is shared with the
+		// caller's value, so it is never cleaned
+		owned := w.owned && !field.viaPtr
+
+		if field.nested && !w.shallow && valueField.IsValid() {
+			if valueField.Kind() != reflect.Ptr {
+				errs = append(errs, prefixErrors(name, w.validateOwned(owned, valueField))...)
+			} else if !valueField.IsNil() {
+				elem, owned := w.own(valueField, owned)
+				errs = append(errs, prefixErrors(name, w.validateOwned(owned, elem))...)
+			}
+		}
+
+		if field.elemNested && !w.shallow && valueField.IsValid() {
+			errs = append(errs, w.validateElems(name, valueField, owned)...)
+		}
+
+		// interface fields are validated by the struct they hold, if any
+		if valueField.Kind() == reflect.Interface && !w.shallow && !valueField.IsNil() {
+			concrete := valueField.Elem()
+			if concrete.Kind() == reflect.Ptr && !concrete.IsNil() {
+				concrete = concrete.Elem()
+			}
+			if concrete.Kind() == reflect.Struct && concrete.Type() != timeType {
+				errs = append(errs, prefixErrors(name, w.validateOwned(false, concrete))...)
+			}
+		}
+
+		if !field.tagged {
+			continue
+		}
+
+		fieldErrs := len(errs)
+		fieldValue := valueField
+		if valueField.Kind() == reflect.Interface {
+			valueField = valueField.Elem()
+		}
+		if valueField.Kind() == reflect.Ptr {
+			valueField = valueField.Elem()
+		}
+
+		atDefault := w.skipDefaults && field.hasDef && isDefault(valueField, field.def)
+		skip := false
+		for _, rule := range field.rules {
+			if w.stop(errs) {
+				break
+			}
+			if rule.invalid {
+				errs = append(errs, ValidationError{FieldName: name, Err: rule.syntaxErr})
+				continue
+			}
+			// trim hands the rules after it a trimmed copy of the string,
+			// the field itself is left as it is
+			if rule.name == "trim" {
+				if valueField.IsValid() {
+					valueField = reflect.ValueOf(strings.TrimSpace(valueField.String())).Convert(valueField.Type())
+					if w.clean && owned && fieldValue.CanSet() {
+						setCleaned(fieldValue, valueField)
+					}
+					fieldValue = valueField
+				}
+				continue
+			}
+			if skip || (atDefault && rule.name != "required" && rule.name != "required_if") {
+				continue
+			}
+			if rule.name == "dive" {
+				continue
+			}
+			// omitempty skips the rules after it when the field is left empty, like in encoding/json
+			if rule.name == "omitempty" {
+				skip = isEmpty(fieldValue)
+				continue
+			}
+			if rule.name == "required_if" {
+				sibling, _ := valueStruct.FieldByIndexErr(rule.sibling)
+				if sibling.Kind() == reflect.Ptr {
+					sibling = sibling.Elem()
+				}
+				if isDefault(sibling, strings.Fields(rule.arg)[1]) && (!fieldValue.IsValid() || fieldValue.IsZero()) {
+					errs = append(errs, ValidationError{FieldName: name, Err: &RuleError{Rule: rule.name, Arg: rule.arg}})
+				}
+				continue
+			}
+			if rule.name == "required" {
+				if !fieldValue.IsValid() || fieldValue.IsZero() {
+					errs = append(errs, ValidationError{FieldName: name, Err: &RuleError{Rule: "required"}})
+				} else if nilElems := countNilElems(valueField); nilElems > 0 {
+					errs = append(errs, ValidationError{FieldName: name, Err: &RuleError{Rule: "required", detail: fmt.Sprintf("%d nil elements", nilElems)}})
+				}
+				continue
+			}
+			// a nil pointer is an absent optional value: only required applies to it
+			if !valueField.IsValid() {
+				continue
+			}
+
+			if rule.container {
+				if err := validateContainerLen(valueField.Len(), rule.name, rule.arg); err != nil {
+					errs = append(errs, ValidationError{FieldName: name, Err: err})
+				}
+				continue
+			}
+
+			if rule.sibling != nil {
+				sibling, _ := valueStruct.FieldByIndexErr(rule.sibling)
+				if sibling.Kind() == reflect.Ptr {
+					sibling = sibling.Elem()
+				}
+				// an unset sibling leaves nothing to compare against
+				if !sibling.IsValid() {
+					continue
+				}
+				if err := validateField(valueField, sibling, rule.name, rule.arg); err != nil {
+					errs = append(errs, ValidationError{FieldName: name, Err: err})
+				}
+				continue
+			}
+
+			fn := rule.fn
+			if fn == nil {
+				continue
+			}
+
+			arg := rule.arg
+			if rule.bound != nil {
+				bound, _ := valueStruct.FieldByIndexErr(rule.bound)
+				if bound.Kind() == reflect.Ptr {
+					bound = bound.Elem()
+				}
+				// an unset bound leaves nothing to compare against
+				if !bound.IsValid() {
+					continue
+				}
+				if bound.CanInt() {
+					arg = strconv.FormatInt(bound.Int(), 10)
+				} else {
+					arg = strconv.FormatUint(bound.Uint(), 10)
+				}
+			}
+
+			switch valueField.Kind() {
+			case reflect.Slice, reflect.Array:
+				elemType := valueField.Type().Elem()
+				elemPtr := elemType.Kind() == reflect.Ptr
+				if elemPtr {
+					elemType = elemType.Elem()
+				}
+				if isValueType(elemType) {
+					for j := 0; j < valueField.Len() && !w.stop(errs); j++ {
+						elem := valueField.Index(j)
+						if elemPtr {
+							// nil elements are absent, only required rejects them
+							if elem.IsNil() {
+								continue
+							}
+							elem = elem.Elem()
+						}
+						if err := fn(elem, arg); err != nil {
+							errs = append(errs, ValidationError{FieldName: name, Err: err})
+						}
+					}
+				} else {
+					errs = append(errs, ValidationError{FieldName: name, Err: fmt.Errorf("%w: %s of %s", ErrUnsupportedType, valueField.Kind(), elemType)})
+				}
+			case reflect.Map:
+				target := valueField.Type().Elem()
+				if rule.key {
+					target = valueField.Type().Key()
+				}
+				if !isValueType(target) {
+					errs = append(errs, ValidationError{FieldName: name, Err: fmt.Errorf("%w: map of %s", ErrUnsupportedType, target)})
+					break
+				}
+				for _, key := range sortedMapKeys(valueField) {
+					if w.stop(errs) {
+						break
+					}
+					value := key
+					if !rule.key {
+						value = valueField.MapIndex(key)
+					}
+					if err := fn(value, arg); err != nil {
+						if rule.key {
+							err = fmt.Errorf("key: %w", err)
+						}
+						errs = append(errs, ValidationError{FieldName: fmt.Sprintf("%s[%v]", name, key), Err: err})
+					}
+				}
+			default:
+				if err := fn(valueField, arg); err != nil {
+					errs = append(errs, ValidationError{FieldName: name, Err: err})
+				}
+			}
+		}
+
+		if field.msg != "" {
+			for i := fieldErrs; i < len(errs); i++ {
+				if errors.Is(errs[i].Err, ErrInvalidatedField) {
+					errs[i].Message = field.msg
+				}
+			}
+		}
+	}
+
+	if w.firstOnly && len(errs) > 1 {
+		errs = errs[:1]
+	}
+	return errs
+}
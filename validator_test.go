@@ -1,11 +1,263 @@
 package validator
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
+type Code string
+
+type UserID int
+
+type Status string
+
+type Age uint8
+
+type Flag bool
+
+type Ratio float32
+
+type MyStrings []string
+
+type Location struct {
+	Code string `validate:"len:3"`
+}
+
+type Base struct {
+	ID   int    `validate:"min:1"`
+	Name string `validate:"len:3"`
+}
+
+type Audit struct {
+	Name string `validate:"len:5"`
+	By   string `validate:"required"`
+}
+
+type hidden struct {
+	Secret string `validate:"len:3"`
+}
+
+type Address struct {
+	Zip  string `validate:"len:5"`
+	City Location
+}
+
+func TestValidateSyntax(t *testing.T) {
+	tests := []struct {
+		tag  string
+		kind reflect.Kind
+		ok   bool
+	}{
+		{tag: "required", kind: reflect.String, ok: true},
+		{tag: "min:3;min:5", kind: reflect.Int},
+		{tag: "required;min:3;required", kind: reflect.Int},
+		{tag: "min:3;max:5", kind: reflect.Int, ok: true},
+		{tag: "required:true", kind: reflect.String},
+		{tag: "omitempty", kind: reflect.String, ok: true},
+		{tag: "omitempty:1", kind: reflect.String},
+		{tag: "gt:0", kind: reflect.Int, ok: true},
+		{tag: "lte:-5", kind: reflect.Uint8, ok: true},
+		{tag: "gte:1.5", kind: reflect.Int},
+		{tag: "lt:3", kind: reflect.Float64},
+		{tag: "gt:3", kind: reflect.String, ok: true},
+		{tag: "gte:abb", kind: reflect.String, ok: true},
+		{tag: "gte:abb", kind: reflect.Int},
+		{tag: "gte:", kind: reflect.String},
+		{tag: "lt:3", kind: reflect.Bool},
+		{tag: "lt", kind: reflect.Int},
+		{tag: "email", kind: reflect.String, ok: true},
+		{tag: "email:x", kind: reflect.String},
+		{tag: "email", kind: reflect.Int},
+		{tag: "url", kind: reflect.String, ok: true},
+		{tag: "alpha", kind: reflect.String, ok: true},
+		{tag: "alphanumeric", kind: reflect.String, ok: true},
+		{tag: "numeric", kind: reflect.String, ok: true},
+		{tag: "numeric:1", kind: reflect.String},
+		{tag: "oneof:red green blue", kind: reflect.String, ok: true},
+		{tag: "oneof:1 2 3", kind: reflect.Int, ok: true},
+		{tag: "oneof:1 two", kind: reflect.Int},
+		{tag: "oneof:", kind: reflect.String},
+		{tag: "oneof:   ", kind: reflect.String},
+		{tag: "oneof", kind: reflect.String},
+		{tag: "uuid", kind: reflect.String, ok: true},
+		{tag: "uuid:4", kind: reflect.String},
+		{tag: "uuid", kind: reflect.Uint64},
+		{tag: "password:min=8,upper=1,digit=1,special=1", kind: reflect.String, ok: true},
+		{tag: "password:lower=2", kind: reflect.String, ok: true},
+		{tag: "trim;min:3", kind: reflect.String, ok: true},
+		{tag: "trim", kind: reflect.Int},
+		{tag: "trim:all", kind: reflect.String},
+		{tag: "required_if:F premium", kind: reflect.String, ok: true},
+		{tag: "required_if:Type premium", kind: reflect.String},
+		{tag: "required_if:Type", kind: reflect.String},
+		{tag: "in:1+2i,3", kind: reflect.Complex128, ok: true},
+		{tag: "required;oneof:(1+2i) -1i", kind: reflect.Complex64, ok: true},
+		{tag: "in:1+2i,x", kind: reflect.Complex128},
+		{tag: "min:1;dive;min:2", kind: reflect.Slice, ok: true},
+		{tag: "min:1;dive;min:2", kind: reflect.Int},
+		{tag: "min:1;dive;min:2;min:3", kind: reflect.Slice},
+		{tag: "min:1", kind: reflect.Complex128},
+		{tag: "min:-10;max:-1", kind: reflect.Int, ok: true},
+		{tag: "in:-1,0,1", kind: reflect.Int64, ok: true},
+		{tag: "range:-5--1", kind: reflect.Int, ok: true},
+		{tag: "min:--1", kind: reflect.Int},
+		{tag: "in:-1,-", kind: reflect.Int},
+		{tag: "max:1", kind: reflect.Complex64},
+		{tag: "required_if:Type a b", kind: reflect.String},
+		{tag: "password", kind: reflect.String},
+		{tag: "password:min=8,capital=1", kind: reflect.String},
+		{tag: "password:min=x", kind: reflect.String},
+		{tag: "password:min", kind: reflect.String},
+		{tag: "password:min=-1", kind: reflect.String},
+		{tag: "password:min=8", kind: reflect.Int},
+		{tag: "numeric", kind: reflect.Int},
+		{tag: "url:http", kind: reflect.String},
+		{tag: "url", kind: reflect.Bool},
+		{tag: "len:3", kind: reflect.String, ok: true},
+		{tag: "len", kind: reflect.String},
+		{tag: "len:", kind: reflect.String},
+		{tag: "len:1.5", kind: reflect.String},
+		{tag: "min:-3", kind: reflect.Int, ok: true},
+		{tag: "min:1.5", kind: reflect.Int},
+		{tag: "min:1.5", kind: reflect.Float64, ok: true},
+		{tag: "max:18446744073709551615", kind: reflect.Uint64, ok: true},
+		{tag: "max:abc", kind: reflect.Int},
+		{tag: "minlen:1", kind: reflect.Slice, ok: true},
+		{tag: "minlen:1", kind: reflect.String},
+		{tag: "maxlen:x", kind: reflect.String},
+		{tag: "range:1-10", kind: reflect.Int, ok: true},
+		{tag: "range:10-1", kind: reflect.Int},
+		{tag: "range:1-10", kind: reflect.String},
+		{tag: "in:a,b", kind: reflect.String, ok: true},
+		{tag: "in:1,b", kind: reflect.Int},
+		{tag: "in:", kind: reflect.String},
+		{tag: "notin:a", kind: reflect.String, ok: true},
+		{tag: "contains:a,b", kind: reflect.String, ok: true},
+		{tag: "prefix:", kind: reflect.String},
+		{tag: "suffix:.com", kind: reflect.String, ok: true},
+		{tag: "regexp:^a:b$", kind: reflect.String, ok: true},
+		{tag: "regexp:[a", kind: reflect.String},
+		{tag: "eq:true", kind: reflect.Bool, ok: true},
+		{tag: "eq:1", kind: reflect.Bool},
+		{tag: "after:2020-01-01", kind: reflect.Struct, ok: true},
+		{tag: "before:2020-01-01T10:00:00Z", kind: reflect.Struct, ok: true},
+		{tag: "before:tomorrow", kind: reflect.Struct},
+		{tag: "regexp:^a", kind: reflect.Int},
+		{tag: "contains:a", kind: reflect.Int},
+		{tag: "len:3", kind: reflect.Int},
+		{tag: "len:3", kind: reflect.Slice, ok: true},
+		{tag: "runemin:1", kind: reflect.Int},
+		{tag: "before:2020-01-01", kind: reflect.Int},
+		{tag: "before:2020-01-01", kind: reflect.String},
+		{tag: "eq:true", kind: reflect.String},
+		{tag: "min:1", kind: reflect.Struct},
+		{tag: "in:1,2", kind: reflect.Struct},
+		{tag: "min:1", kind: reflect.Bool},
+		{tag: "prefix:a", kind: reflect.Float64},
+		{tag: "in:1,2", kind: reflect.Complex128, ok: true},
+		{tag: "min:1", kind: reflect.Complex128},
+		{tag: "mn:3", kind: reflect.String},
+		{tag: "key:min:1", kind: reflect.String},
+		{tag: "min:1;", kind: reflect.Int},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			_, ok := tagSyntax(tt.tag, tt.kind)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+
+	rule, ok := tagSyntax("min:3;max:foo;in:a", reflect.Int)
+	assert.False(t, ok)
+	assert.Equal(t, "max:foo", rule)
+}
+
+// tagSyntax checks tag as the walker does, on the only field of a struct,
+// of a type of kind. It returns ok == false and the first malformed or
+// repeated rule otherwise.
+func tagSyntax(tag string, kind reflect.Kind) (rule string, ok bool) {
+	types := map[reflect.Kind]reflect.Type{
+		reflect.String:     reflect.TypeOf(""),
+		reflect.Int:        reflect.TypeOf(0),
+		reflect.Int64:      reflect.TypeOf(int64(0)),
+		reflect.Uint8:      reflect.TypeOf(uint8(0)),
+		reflect.Uint64:     reflect.TypeOf(uint64(0)),
+		reflect.Float64:    reflect.TypeOf(0.0),
+		reflect.Bool:       reflect.TypeOf(false),
+		reflect.Complex64:  reflect.TypeOf(complex64(0)),
+		reflect.Complex128: reflect.TypeOf(complex128(0)),
+		reflect.Struct:     timeType,
+		reflect.Slice:      reflect.TypeOf([]string(nil)),
+	}
+	typ := reflect.StructOf([]reflect.StructField{{
+		Name: "F",
+		Type: types[kind],
+		Tag:  reflect.StructTag("validate:" + strconv.Quote(tag)),
+	}})
+	for _, r := range defaultValidator.structMeta(typ, defaultTagName).fields[0].rules {
+		if r.invalid {
+			return r.raw, false
+		}
+	}
+	return "", true
+}
+
+func TestParseTag(t *testing.T) {
+	rules, err := ParseTag("required;min:3;regexp:^\\d{2}:\\d{2}$;in:a,b")
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Name: "required"},
+		{Name: "min", Arg: "3"},
+		{Name: "regexp", Arg: "^\\d{2}:\\d{2}$"},
+		{Name: "in", Arg: "a,b"},
+	}, rules)
+
+	rules, err = ParseTag("")
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	rules, err = ParseTag("key:min:2;value:min:0;min:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Name: "min", Arg: "2", Target: "key"},
+		{Name: "min", Arg: "0", Target: "value"},
+		{Name: "min", Arg: "1"},
+	}, rules)
+	rules, err = ParseTag("min:$MinAge;max:$Max_2")
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{{Name: "min", Arg: "$MinAge"}, {Name: "max", Arg: "$Max_2"}}, rules)
+	rules, err = ParseTag("key=min:2;value=len:3")
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{{Name: "min", Arg: "2", Target: "key"}, {Name: "len", Arg: "3", Target: "value"}}, rules)
+
+	_, err = ParseTag("min:3;;max:5")
+	assert.ErrorIs(t, err, ErrEmptyRule)
+	assert.EqualError(t, err, "invalid validator syntax: empty rule at position 2")
+	_, err = ParseTag("min:3;mx:5")
+	assert.ErrorIs(t, err, ErrUnknownRule)
+	_, err = ParseTag("min:abc")
+	assert.ErrorIs(t, err, ErrInvalidValidatorSyntax)
+	assert.NotErrorIs(t, err, ErrEmptyRule)
+	assert.NotErrorIs(t, err, ErrUnknownRule)
+
+	for _, tag := range []string{"min:3;max:foo", "mn:3", "len", "in:", "min:3;", "min:3;min:5", "regexp:[a-z", "eq:yes", "after:yesterday", "key:min:1;key=min:2", "key:mn:2", "min:$", "max:$1st"} {
+		rules, err = ParseTag(tag)
+		assert.ErrorIs(t, err, ErrInvalidValidatorSyntax, tag)
+		assert.Nil(t, rules, tag)
+	}
+}
+
 func TestValidate(t *testing.T) {
 	type args struct {
 		v any
@@ -33,7 +285,7 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 			checkErr: func(err error) bool {
-				return errors.Is(err, ErrNotStruct)
+				return errors.Is(err, ErrNotStruct) && err.Error() == "wrong argument given, should be a struct: got map"
 			},
 		},
 		{
@@ -43,7 +295,28 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 			checkErr: func(err error) bool {
-				return errors.Is(err, ErrNotStruct)
+				return errors.Is(err, ErrNotStruct) && err.Error() == "wrong argument given, should be a struct: got string"
+			},
+		},
+		{
+			name: "invalid struct: nil pointer",
+			args: args{
+				v: (*Address)(nil),
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				return errors.Is(err, ErrNotStruct) && err.Error() == "wrong argument given, should be a struct: got nil pointer"
+			},
+		},
+		{
+			name: "pointer to struct",
+			args: args{
+				v: &Address{Zip: "1234", City: Location{Code: "abc"}},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 1 && e[0].FieldName == "Zip"
 			},
 		},
 		{
@@ -73,7 +346,7 @@ func TestValidate(t *testing.T) {
 			wantErr: true,
 			checkErr: func(err error) bool {
 				e := &ValidationErrors{}
-				return errors.As(err, e) && e.Error() == ErrValidateForUnexportedFields.Error()
+				return errors.As(err, e) && e.Error() == "[foo]: "+ErrValidateForUnexportedFields.Error()+"\n"
 			},
 		},
 		{
@@ -85,9 +358,8 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 			checkErr: func(err error) bool {
-
 				e := &ValidationErrors{}
-				return errors.As(err, e) && e.Error() == ErrInvalidValidatorSyntax.Error()
+				return errors.As(err, e) && e.Error() == "[Foo]: "+ErrInvalidValidatorSyntax.Error()+": \"len:abcdef\"\n"
 			},
 		},
 		{
@@ -165,8 +437,9 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 			checkErr: func(err error) bool {
-				assert.Len(t, err.(ValidationErrors), 5)
-				return true
+				e := err.(ValidationErrors)
+				assert.Len(t, e, 5)
+				return errors.Is(e[3].Err, ErrInvalidValidatorSyntax)
 			},
 		},
 		{
@@ -240,6 +513,210 @@ func TestValidate(t *testing.T) {
 				return true
 			},
 		},
+		{
+			name: "sized ints",
+			args: args{
+				v: struct {
+					I8  int8  `validate:"min:-128;max:127"`
+					I16 int16 `validate:"in:-300,300"`
+					I32 int32 `validate:"max:10"`
+					I64 int64 `validate:"in:9223372036854775807,-9223372036854775808"`
+				}{
+					I8:  -128,
+					I16: 300,
+					I32: 11,
+					I64: -9223372036854775808,
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 1 && e[0].FieldName == "I32" &&
+					errors.Is(e[0].Err, ErrInvalidatedField)
+			},
+		},
+		{
+			name: "unsigned ints",
+			args: args{
+				v: struct {
+					U   uint   `validate:"min:-1;max:8080"`
+					U8  uint8  `validate:"in:1,2,3"`
+					U16 uint16 `validate:"min:1024"`
+					U32 uint32 `validate:"max:-1"`
+					U64 uint64 `validate:"min:18446744073709551615"`
+				}{
+					U:   8080,
+					U8:  2,
+					U16: 80,
+					U32: 0,
+					U64: 18446744073709551615,
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 2 &&
+					e[0].FieldName == "U16" && e[1].FieldName == "U32"
+			},
+		},
+		{
+			name: "floats",
+			args: args{
+				v: struct {
+					Price    float64 `validate:"min:1.5;max:10"`
+					Ratio    float32 `validate:"in:0.5,0.25"`
+					Discount float64 `validate:"max:0.3"`
+					Bad      float64 `validate:"min:abc"`
+					IntBound int     `validate:"min:1.5"`
+				}{
+					Price:    1.5,
+					Ratio:    0.25,
+					Discount: 0.31,
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 3 &&
+					e[0].FieldName == "Discount" && errors.Is(e[0].Err, ErrInvalidatedField) &&
+					e[1].FieldName == "Bad" && errors.Is(e[1].Err, ErrInvalidValidatorSyntax) &&
+					e[2].FieldName == "IntBound" && errors.Is(e[2].Err, ErrInvalidValidatorSyntax)
+			},
+		},
+		{
+			name: "regexp",
+			args: args{
+				v: struct {
+					Lower string `validate:"regexp:^[a-z]+$"`
+					Time  string `validate:"regexp:^\\d{2}:\\d{2}$"`
+					Bad   string `validate:"regexp:[a-z"`
+					Upper string `validate:"regexp:^[A-Z]+$"`
+				}{
+					Lower: "abc",
+					Time:  "12:30",
+					Upper: "abc",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 2 &&
+					e[0].FieldName == "Bad" && errors.Is(e[0].Err, ErrInvalidValidatorSyntax) &&
+					e[1].FieldName == "Upper" && errors.Is(e[1].Err, ErrInvalidatedField)
+			},
+		},
+		{
+			name: "in with colons",
+			args: args{
+				v: struct {
+					Pair  string `validate:"in:foo:bar,baz"`
+					Split string `validate:"in:foo:bar,baz"`
+				}{
+					Pair:  "foo:bar",
+					Split: "foo",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 1 && e[0].FieldName == "Split"
+			},
+		},
+		{
+			name: "field names in errors",
+			args: args{
+				v: struct {
+					Name string `validate:"len:3"`
+					Code `validate:"in:foo,bar"`
+				}{
+					Name: "ab",
+					Code: "baz",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				if !errors.As(err, &e) || len(e) != 2 {
+					return false
+				}
+				return e[0].FieldName == "Name" && e[1].FieldName == "Code" &&
+					e.Error() == "[Name]: field invalidated: len:3 failed (length 2)\n"+
+						"[Code]: field invalidated: in:foo,bar failed (value \"baz\")\n"
+			},
+		},
+		{
+			name: "nested structs",
+			args: args{
+				v: struct {
+					Name    string `validate:"min:1"`
+					Address Address
+					Billing Address
+				}{
+					Name:    "bob",
+					Address: Address{Zip: "1234", City: Location{Code: "ab"}},
+					Billing: Address{Zip: "12345", City: Location{Code: "abc"}},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 2 &&
+					e[0].FieldName == "Address.Zip" && e[1].FieldName == "Address.City.Code" &&
+					e.Error() == "[Address.Zip]: field invalidated: len:5 failed (length 4)\n"+
+						"[Address.City.Code]: field invalidated: len:3 failed (length 2)\n"
+			},
+		},
+		{
+			name: "pointer fields",
+			args: args{
+				v: struct {
+					NilStr     *string `validate:"len:3"`
+					NilInt     *int    `validate:"min:10"`
+					NilAddress *Address
+					NilNoRule  *string
+					Str        *string `validate:"len:3"`
+					Int        *int    `validate:"min:10"`
+					Address    *Address
+				}{
+					Str:     func() *string { s := "abcd"; return &s }(),
+					Int:     func() *int { i := 10; return &i }(),
+					Address: &Address{Zip: "123", City: Location{Code: "abc"}},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 2 &&
+					e[0].FieldName == "Str" && e[1].FieldName == "Address.Zip"
+			},
+		},
+		{
+			name: "required",
+			args: args{
+				v: struct {
+					Str      string   `validate:"required"`
+					Int      int      `validate:"required;min:-5"`
+					Ptr      *string  `validate:"required;len:3"`
+					Slice    []string `validate:"required"`
+					Set      string   `validate:"required;len:3"`
+					SetPtr   *int     `validate:"required"`
+					SetSlice []int    `validate:"required"`
+					BadArg   string   `validate:"required:true"`
+				}{
+					Set:      "abc",
+					SetPtr:   new(int),
+					SetSlice: []int{},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 5 &&
+					e[0].FieldName == "Str" && e[1].FieldName == "Int" &&
+					e[2].FieldName == "Ptr" && e[3].FieldName == "Slice" &&
+					e[4].FieldName == "BadArg" && errors.Is(e[4].Err, ErrInvalidValidatorSyntax)
+			},
+		},
 		{
 			name: "slice correct int",
 			args: args{
@@ -281,18 +758,2822 @@ func TestValidate(t *testing.T) {
 				return true
 			},
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := Validate(tt.args.v)
-			if tt.wantErr {
-				assert.Error(t, err)
-				assert.True(t, tt.checkErr(err), "test expect an error, but got wrong error type")
-
-			} else {
-				assert.NoError(t, err)
-			}
-		})
-	}
+		{
+			name: "slice length",
+			args: args{
+				v: struct {
+					Short []string `validate:"minlen:3"`
+					Long  []int    `validate:"maxlen:2;min:1"`
+					Fits  []string `validate:"minlen:1;maxlen:2;len:2"`
+					Bad   []string `validate:"minlen:abc"`
+				}{
+					Short: []string{"a", "b"},
+					Long:  []int{1, 2, 3},
+					Fits:  []string{"a", "b"},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 3 &&
+					e[0].FieldName == "Short" && e[1].FieldName == "Long" &&
+					e[2].FieldName == "Bad" && errors.Is(e[2].Err, ErrInvalidValidatorSyntax)
+			},
+		},
+		{
+			name: "slice element kinds",
+			args: args{
+				v: struct {
+					Int64s []int64    `validate:"min:0"`
+					Uints  []uint16   `validate:"max:10"`
+					Floats []float64  `validate:"max:1.5"`
+					Chans  []chan int `validate:"min:1"`
+				}{
+					Int64s: []int64{0, -1},
+					Uints:  []uint16{1, 11},
+					Floats: []float64{1.5, 1.6},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 4 &&
+					e[0].FieldName == "Int64s" && e[1].FieldName == "Uints" && e[2].FieldName == "Floats" &&
+					e[3].FieldName == "Chans" && errors.Is(e[3].Err, ErrUnsupportedType) &&
+					e[3].Err.Error() == "type not supported: slice of chan int"
+			},
+		},
+		{
+			name: "bool eq",
+			args: args{
+				v: struct {
+					TermsAccepted bool   `validate:"eq:true"`
+					Banned        bool   `validate:"eq:false"`
+					Flags         []bool `validate:"eq:true"`
+					Bad           bool   `validate:"eq:yes"`
+				}{
+					TermsAccepted: false,
+					Banned:        false,
+					Flags:         []bool{true, false},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 3 &&
+					e[0].FieldName == "TermsAccepted" && e[1].FieldName == "Flags" &&
+					e[2].FieldName == "Bad" && errors.Is(e[2].Err, ErrInvalidValidatorSyntax)
+			},
+		},
+		{
+			name: "malformed rule does not suppress others",
+			args: args{
+				v: struct {
+					Name string `validate:"min:3;max:foo;in:a,b"`
+				}{
+					Name: "c",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 3 &&
+					errors.Is(e[0].Err, ErrInvalidatedField) &&
+					errors.Is(e[1].Err, ErrInvalidValidatorSyntax) &&
+					errors.Is(e[2].Err, ErrInvalidatedField) &&
+					e.Error() == "[Name]: field invalidated: min:3 failed (length 1)\n"+
+						"[Name]: invalid validator syntax: \"max:foo\"\n"+
+						"[Name]: field invalidated: in:a,b failed (value \"c\")\n"
+			},
+		},
+		{
+			name: "contains",
+			args: args{
+				v: struct {
+					Plain   string `validate:"contains:abc"`
+					Comma   string `validate:"contains:a,b"`
+					Missing string `validate:"contains:abc"`
+					Empty   string `validate:"contains:"`
+				}{
+					Plain:   "xxabcxx",
+					Comma:   "1a,b2",
+					Missing: "ab,c",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 2 &&
+					e[0].FieldName == "Missing" && errors.Is(e[0].Err, ErrInvalidatedField) &&
+					e[1].FieldName == "Empty" && errors.Is(e[1].Err, ErrInvalidValidatorSyntax)
+			},
+		},
+		{
+			name: "prefix and suffix",
+			args: args{
+				v: struct {
+					URL         string `validate:"prefix:https://;suffix:.com"`
+					HTTP        string `validate:"prefix:https://"`
+					Domain      string `validate:"suffix:.com"`
+					EmptyPrefix string `validate:"prefix:"`
+					EmptySuffix string `validate:"suffix:"`
+				}{
+					URL:    "https://example.com",
+					HTTP:   "http://example.com",
+					Domain: "example.org",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 4 &&
+					e[0].FieldName == "HTTP" && errors.Is(e[0].Err, ErrInvalidatedField) &&
+					e[1].FieldName == "Domain" && errors.Is(e[1].Err, ErrInvalidatedField) &&
+					e[2].FieldName == "EmptyPrefix" && errors.Is(e[2].Err, ErrInvalidValidatorSyntax) &&
+					e[3].FieldName == "EmptySuffix" && errors.Is(e[3].Err, ErrInvalidValidatorSyntax)
+			},
+		},
+		{
+			name: "failure messages",
+			args: args{
+				v: struct {
+					Age      int     `validate:"min:18"`
+					Count    uint    `validate:"max:3"`
+					Price    float64 `validate:"in:1.5,2.5"`
+					Accepted bool    `validate:"eq:true"`
+					Tags     []int   `validate:"minlen:2"`
+					Name     string  `validate:"required"`
+				}{
+					Age:   16,
+					Count: 4,
+					Price: 2,
+					Tags:  []int{1},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				if !errors.As(err, &e) || len(e) != 6 {
+					return false
+				}
+				for _, ve := range e {
+					if !errors.Is(ve.Err, ErrInvalidatedField) {
+						return false
+					}
+				}
+				return e.Error() == "[Age]: field invalidated: min:18 failed (value 16)\n"+
+					"[Count]: field invalidated: max:3 failed (value 4)\n"+
+					"[Price]: field invalidated: in:1.5,2.5 failed (value 2)\n"+
+					"[Accepted]: field invalidated: eq:true failed (value false)\n"+
+					"[Tags]: field invalidated: minlen:2 failed (length 1)\n"+
+					"[Name]: field invalidated: required failed\n"
+			},
+		},
+		{
+			name: "time before and after",
+			args: args{
+				v: struct {
+					CreatedAt time.Time   `validate:"after:2020-01-01"`
+					ExpiresAt *time.Time  `validate:"before:2030-01-01T00:00:00Z"`
+					Old       time.Time   `validate:"after:2020-01-01"`
+					Zero      time.Time   `validate:"required"`
+					Dates     []time.Time `validate:"before:2021-01-01"`
+					Bad       time.Time   `validate:"after:yesterday"`
+				}{
+					CreatedAt: time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC),
+					ExpiresAt: func() *time.Time { t := time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC); return &t }(),
+					Old:       time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC),
+					Dates:     []time.Time{time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 4 &&
+					e[0].FieldName == "Old" && errors.Is(e[0].Err, ErrInvalidatedField) &&
+					e[1].FieldName == "Zero" && errors.Is(e[1].Err, ErrInvalidatedField) &&
+					e[2].FieldName == "Dates" && errors.Is(e[2].Err, ErrInvalidatedField) &&
+					e[3].FieldName == "Bad" && errors.Is(e[3].Err, ErrInvalidValidatorSyntax)
+			},
+		},
+		{
+			name: "embedded structs",
+			args: args{
+				v: struct {
+					Base
+					Name string `validate:"len:4"`
+				}{
+					Base: Base{ID: 0, Name: "x"},
+					Name: "abcd",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				// Base.Name is shadowed by the outer Name, so only ID is reported from Base
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 1 && e[0].FieldName == "ID"
+			},
+		},
+		{
+			name: "embedded structs: ambiguous, pointer and unexported",
+			args: args{
+				v: struct {
+					Base
+					*Audit
+					hidden
+				}{
+					Base:   Base{ID: 1, Name: "x"},
+					hidden: hidden{Secret: "x"},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				// Name is ambiguous between Base and Audit, By is promoted through a nil pointer,
+				// and hidden is an unexported embedded struct
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 1 && e[0].FieldName == "By"
+			},
+		},
+		{
+			name: "range",
+			args: args{
+				v: struct {
+					InRange  int    `validate:"range:1-10"`
+					Low      int8   `validate:"range:1-10"`
+					High     int64  `validate:"range:1-10"`
+					Negative int    `validate:"range:-10--5"`
+					Unsigned uint   `validate:"range:-1-18446744073709551615"`
+					UintHigh uint16 `validate:"range:0-100"`
+					Huge     int64  `validate:"range:0-18446744073709551615"`
+					Slice    []int  `validate:"range:0-1"`
+				}{
+					InRange:  10,
+					Low:      0,
+					High:     11,
+					Negative: -7,
+					Unsigned: 5,
+					UintHigh: 101,
+					Huge:     9223372036854775807,
+					Slice:    []int{0, 1, 2},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 4 &&
+					e[0].FieldName == "Low" && e[1].FieldName == "High" &&
+					e[2].FieldName == "UintHigh" && e[3].FieldName == "Slice" &&
+					e[1].Err.Error() == "field invalidated: range:1-10 failed (value 11)"
+			},
+		},
+		{
+			name: "range syntax",
+			args: args{
+				v: struct {
+					Reversed int    `validate:"range:10-1"`
+					Open     int    `validate:"range:5-"`
+					Single   int    `validate:"range:5"`
+					NotInt   int    `validate:"range:a-b"`
+					Float    int    `validate:"range:1.5-2"`
+					String   string `validate:"range:1-2"`
+				}{},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				if !errors.As(err, &e) || len(e) != 6 {
+					return false
+				}
+				for _, ve := range e {
+					if !errors.Is(ve.Err, ErrInvalidValidatorSyntax) {
+						return false
+					}
+				}
+				return true
+			},
+		},
+		{
+			name: "numeric in candidates",
+			args: args{
+				v: struct {
+					Int      int     `validate:"in:1,two,3"`
+					Uint     uint    `validate:"in:1,2.5"`
+					Float    float64 `validate:"in:1.5,x"`
+					Negative uint    `validate:"in:-1,1"`
+					Text     string  `validate:"in:1,two"`
+				}{
+					Negative: 1,
+					Text:     "two",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 3 &&
+					errors.Is(e[0].Err, ErrInvalidValidatorSyntax) && e[0].FieldName == "Int" &&
+					errors.Is(e[1].Err, ErrInvalidValidatorSyntax) && e[1].FieldName == "Uint" &&
+					errors.Is(e[2].Err, ErrInvalidValidatorSyntax) && e[2].FieldName == "Float"
+			},
+		},
+		{
+			name: "maps",
+			args: args{
+				v: struct {
+					Scores   map[string]int    `validate:"value:min:0"`
+					Labels   map[string]string `validate:"in:a,b;key=len:2"`
+					Counts   map[int]uint      `validate:"maxlen:1;key=min:0"`
+					Nested   map[string][]int  `validate:"value:min:0"`
+					BadKey   []int             `validate:"key=min:0"`
+					BadValue map[string]int    `validate:"len:x"`
+				}{
+					Scores: map[string]int{"bob": -1, "alice": 3, "eve": -2},
+					Labels: map[string]string{"ab": "a", "abc": "c"},
+					Counts: map[int]uint{-1: 1, 2: 2},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				if !errors.As(err, &e) || len(e) != 9 {
+					return false
+				}
+				names := make([]string, len(e))
+				for i, ve := range e {
+					names[i] = ve.FieldName
+				}
+				return assert.Equal(t, []string{
+					"Scores[bob]", "Scores[eve]", "Labels[abc]", "Labels[abc]",
+					"Counts", "Counts[-1]", "Nested", "BadKey", "BadValue",
+				}, names) && errors.Is(e[6].Err, ErrUnsupportedType) &&
+					errors.Is(e[7].Err, ErrInvalidValidatorSyntax) && errors.Is(e[8].Err, ErrInvalidValidatorSyntax)
+			},
+		},
+		{
+			name: "notin",
+			args: args{
+				v: struct {
+					Username string   `validate:"notin:admin,root,system"`
+					Reserved string   `validate:"notin:admin,root,system"`
+					Port     int      `validate:"notin:22,23"`
+					Uint     uint     `validate:"notin:0"`
+					Ratio    float64  `validate:"notin:0.5"`
+					Names    []string `validate:"notin:root"`
+					Empty    string   `validate:"notin:"`
+					NotInt   int      `validate:"notin:a"`
+				}{
+					Username: "gopher",
+					Reserved: "root",
+					Port:     22,
+					Uint:     1,
+					Ratio:    0.5,
+					Names:    []string{"bob", "root"},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 6 &&
+					e[0].FieldName == "Reserved" && e[0].Err.Error() == `field invalidated: notin:admin,root,system failed (value "root")` &&
+					e[1].FieldName == "Port" && e[2].FieldName == "Ratio" && e[3].FieldName == "Names" &&
+					e[4].FieldName == "Empty" && errors.Is(e[4].Err, ErrInvalidValidatorSyntax) &&
+					e[5].FieldName == "NotInt" && errors.Is(e[5].Err, ErrInvalidValidatorSyntax)
+			},
+		},
+		{
+			name: "omitempty",
+			args: args{
+				v: struct {
+					Empty    string   `validate:"omitempty;min:3"`
+					Short    string   `validate:"omitempty;min:3"`
+					Zero     int      `validate:"omitempty;min:1"`
+					NilPtr   *int     `validate:"omitempty;min:1"`
+					NoSlice  []string `validate:"omitempty;minlen:1"`
+					Before   string   `validate:"len:2;omitempty;min:3"`
+					BadAfter string   `validate:"omitempty;min:x"`
+					BadArg   string   `validate:"omitempty:true"`
+				}{
+					Short:   "ab",
+					NoSlice: []string{},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 4 &&
+					e[0].FieldName == "Short" && e[1].FieldName == "Before" &&
+					e[2].FieldName == "BadAfter" && errors.Is(e[2].Err, ErrInvalidValidatorSyntax) &&
+					e[3].FieldName == "BadArg" && errors.Is(e[3].Err, ErrInvalidValidatorSyntax)
+			},
+		},
+		{
+			name: "arrays",
+			args: args{
+				v: struct {
+					Ints    [3]int       `validate:"min:1"`
+					Strings [2]string    `validate:"len:2;maxlen:2"`
+					Short   [1]int       `validate:"minlen:2"`
+					Ptr     *[2]uint     `validate:"max:5"`
+					Chans   [1]chan bool `validate:"min:1"`
+				}{
+					Ints:    [3]int{1, 0, 2},
+					Strings: [2]string{"ab", "cd"},
+					Ptr:     &[2]uint{5, 6},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 4 &&
+					e[0].FieldName == "Ints" && e[1].FieldName == "Short" && e[2].FieldName == "Ptr" &&
+					e[3].FieldName == "Chans" && e[3].Err.Error() == "type not supported: array of chan bool"
+			},
+		},
+		{
+			name: "named slice and element types",
+			args: args{
+				v: struct {
+					IDs   []UserID  `validate:"min:1"`
+					Names MyStrings `validate:"len:2;maxlen:2"`
+					Codes []Code    `validate:"in:foo,bar"`
+				}{
+					IDs:   []UserID{1, 0, 5},
+					Names: MyStrings{"bob", "al", "eve"},
+					Codes: []Code{"foo", "baz"},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 4 &&
+					e[0].FieldName == "IDs" && e[0].Err.Error() == "field invalidated: min:1 failed (value 0)" &&
+					e[1].FieldName == "Names" && e[2].FieldName == "Names" && e[3].FieldName == "Codes"
+			},
+		},
+		{
+			name: "named scalar types",
+			args: args{
+				v: struct {
+					Status   Status            `validate:"in:active,inactive"`
+					Previous *Status           `validate:"in:active,inactive"`
+					Age      Age               `validate:"range:18-130"`
+					Flag     Flag              `validate:"eq:true"`
+					Ratio    Ratio             `validate:"max:0.5"`
+					ByID     map[UserID]Status `validate:"notin:banned;key=min:1"`
+				}{
+					Status:   "deleted",
+					Previous: func() *Status { s := Status("active"); return &s }(),
+					Age:      17,
+					Flag:     true,
+					Ratio:    0.75,
+					ByID:     map[UserID]Status{0: "active", 1: "banned"},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 5 &&
+					e[0].FieldName == "Status" && e[0].Err.Error() == `field invalidated: in:active,inactive failed (value "deleted")` &&
+					e[1].FieldName == "Age" && e[2].FieldName == "Ratio" &&
+					e[3].FieldName == "ByID[1]" && e[4].FieldName == "ByID[0]"
+			},
+		},
+		{
+			name: "unknown rule names",
+			args: args{
+				v: struct {
+					Typo    string `validate:"mn:3"`
+					Bare    string `validate:"email_address"`
+					Spaced  int    `validate:" min:1 ; max:5 "`
+					Trailer int    `validate:"min:1;"`
+				}{
+					Spaced:  3,
+					Trailer: 1,
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 3 &&
+					e[0].FieldName == "Typo" && errors.Is(e[0].Err, ErrInvalidValidatorSyntax) &&
+					errors.Is(e[0].Err, ErrUnknownRule) && e[0].Err.Error() == `invalid validator syntax: unknown rule: "mn:3"` &&
+					e[1].FieldName == "Bare" && errors.Is(e[1].Err, ErrUnknownRule) &&
+					e[2].FieldName == "Trailer" && errors.Is(e[2].Err, ErrInvalidValidatorSyntax) &&
+					errors.Is(e[2].Err, ErrEmptyRule) && !errors.Is(e[2].Err, ErrUnknownRule) &&
+					e[2].Err.Error() == "invalid validator syntax: empty rule at position 2"
+			},
+		},
+		{
+			name: "escaped commas in lists",
+			args: args{
+				v: struct {
+					Escaped   string `validate:"in:a\\,b,c"`
+					Split     string `validate:"in:a\\,b,c"`
+					Plain     string `validate:"in:a,b,c"`
+					Backslash string `validate:"in:x\\\\,y"`
+					NotIn     string `validate:"notin:a\\,b"`
+				}{
+					Escaped:   "a,b",
+					Split:     "a",
+					Plain:     "a,b",
+					Backslash: `x\`,
+					NotIn:     "a,b",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 3 &&
+					e[0].FieldName == "Split" && e[1].FieldName == "Plain" && e[2].FieldName == "NotIn"
+			},
+		},
+		{
+			name: "rune count",
+			args: args{
+				v: struct {
+					Bytes    string `validate:"max:4"`
+					Runes    string `validate:"runemax:4"`
+					Emoji    string `validate:"runemin:2;runemax:2"`
+					TooShort string `validate:"runemin:3"`
+					TooLong  string `validate:"runemax:3"`
+				}{
+					Bytes:    "café",
+					Runes:    "café",
+					Emoji:    "👍🏽",
+					TooShort: "né",
+					TooLong:  "ñañá",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 3 &&
+					e[0].FieldName == "Bytes" && e[0].Err.Error() == "field invalidated: max:4 failed (length 5)" &&
+					e[1].FieldName == "TooShort" && e[1].Err.Error() == "field invalidated: runemin:3 failed (length 2)" &&
+					e[2].FieldName == "TooLong" && e[2].Err.Error() == "field invalidated: runemax:3 failed (length 4)"
+			},
+		},
+		{
+			name: "email",
+			args: args{
+				v: struct {
+					Plain    string   `validate:"email"`
+					Tagged   string   `validate:"email"`
+					Optional string   `validate:"omitempty;email"`
+					Named    string   `validate:"email"`
+					Missing  string   `validate:"email"`
+					List     []string `validate:"email"`
+				}{
+					Plain:  "user@example.com",
+					Tagged: "first.last+tag@sub.example.org",
+					Named:  "Bob <bob@example.com>",
+					List:   []string{"a@b.c", "not-an-email"},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 3 &&
+					e[0].FieldName == "Named" && e[0].Err.Error() == `field invalidated: email failed (value "Bob <bob@example.com>")` &&
+					e[1].FieldName == "Missing" && errors.Is(e[1].Err, ErrInvalidatedField) &&
+					e[2].FieldName == "List" && e[2].Err.Error() == `field invalidated: email failed (value "not-an-email")`
+			},
+		},
+		{
+			name: "url",
+			args: args{
+				v: struct {
+					HTTP           string `validate:"url"`
+					HTTPS          string `validate:"url"`
+					SchemeRelative string `validate:"url"`
+					Path           string `validate:"url"`
+					Invalid        string `validate:"url"`
+					NoHost         string `validate:"url"`
+				}{
+					HTTP:           "http://example.com",
+					HTTPS:          "https://example.com:8443/path?q=1",
+					SchemeRelative: "//example.com/path",
+					Path:           "/just/a/path",
+					Invalid:        "not a url",
+					NoHost:         "mailto:user@example.com",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 4 &&
+					e[0].FieldName == "SchemeRelative" && e[0].Err.Error() == `field invalidated: url failed (value "//example.com/path")` &&
+					e[1].FieldName == "Path" && errors.Is(e[1].Err, ErrInvalidatedField) &&
+					e[2].FieldName == "Invalid" && errors.Is(e[2].Err, ErrInvalidatedField) &&
+					e[3].FieldName == "NoHost" && errors.Is(e[3].Err, ErrInvalidatedField)
+			},
+		},
+		{
+			name: "slices of pointers",
+			args: args{
+				v: func() any {
+					one, five, ten := 1, 5, 10
+					name, empty := "abc", ""
+					return struct {
+						Ints     []*int    `validate:"min:5"`
+						Names    []*string `validate:"min:3"`
+						Required []*int    `validate:"required;min:5"`
+						AllNil   []*int    `validate:"min:5"`
+					}{
+						Ints:     []*int{&five, nil, &one, &ten},
+						Names:    []*string{&name, nil, &empty},
+						Required: []*int{nil, &ten, nil},
+						AllNil:   []*int{nil, nil},
+					}
+				}(),
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 3 &&
+					e[0].FieldName == "Ints" && e[0].Err.Error() == "field invalidated: min:5 failed (value 1)" &&
+					e[1].FieldName == "Names" && e[1].Err.Error() == "field invalidated: min:3 failed (length 0)" &&
+					e[2].FieldName == "Required" && e[2].Err.Error() == "field invalidated: required failed (2 nil elements)"
+			},
+		},
+		{
+			name: "cross-field rules",
+			args: args{
+				v: func() any {
+					start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+					return struct {
+						Password        string
+						ConfirmPassword string `validate:"eqfield:Password"`
+						OldPassword     string `validate:"nefield:Password"`
+						StartDate       time.Time
+						EndDate         time.Time `validate:"gtfield:StartDate"`
+						Min             *int
+						Max             int  `validate:"gtfield:Min"`
+						Low             uint `validate:"ltfield:High"`
+						High            uint
+						Missing         string `validate:"eqfield:Nope"`
+						Mismatch        int    `validate:"eqfield:Password"`
+						Unordered       bool   `validate:"gtfield:Flag"`
+						Flag            bool
+					}{
+						Password:        "secret",
+						ConfirmPassword: "secreT",
+						OldPassword:     "secret",
+						StartDate:       start,
+						EndDate:         start,
+						Max:             5,
+						Low:             3,
+						High:            4,
+					}
+				}(),
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 6 &&
+					e[0].FieldName == "ConfirmPassword" && e[0].Err.Error() == `field invalidated: eqfield:Password failed (value "secreT")` &&
+					e[1].FieldName == "OldPassword" && errors.Is(e[1].Err, ErrInvalidatedField) &&
+					e[2].FieldName == "EndDate" && errors.Is(e[2].Err, ErrInvalidatedField) &&
+					e[3].FieldName == "Missing" && errors.Is(e[3].Err, ErrInvalidValidatorSyntax) &&
+					e[4].FieldName == "Mismatch" && errors.Is(e[4].Err, ErrInvalidValidatorSyntax) &&
+					e[5].FieldName == "Unordered" && errors.Is(e[5].Err, ErrInvalidValidatorSyntax)
+			},
+		},
+		{
+			name: "cross-field rules: valid",
+			args: args{
+				v: func() any {
+					start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+					min := 1
+					return struct {
+						Password        string
+						ConfirmPassword string `validate:"eqfield:Password"`
+						StartDate       time.Time
+						EndDate         time.Time `validate:"gtfield:StartDate"`
+						SameInstant     time.Time `validate:"eqfield:StartDate"`
+						Min             *int
+						Max             int `validate:"gtfield:Min"`
+					}{
+						Password:        "secret",
+						ConfirmPassword: "secret",
+						StartDate:       start,
+						EndDate:         start.Add(time.Hour),
+						SameInstant:     start.In(time.FixedZone("UTC+3", 3*60*60)),
+						Min:             &min,
+						Max:             5,
+					}
+				}(),
+			},
+			wantErr: false,
+		},
+		{
+			name: "cross-field rules: interfaces",
+			args: args{
+				v: struct {
+					A any `validate:"eqfield:B"`
+					B any
+					C any `validate:"nefield:B"`
+					N any `validate:"eqfield:M"`
+					M any
+				}{A: []int{1}, B: []int{1}, C: 1, N: 2, M: 2},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && assert.Len(t, e, 2) &&
+					assert.EqualError(t, e[0].Err, "type not supported: []int") &&
+					assert.ErrorIs(t, e[1].Err, ErrUnsupportedType)
+			},
+		},
+		{
+			name: "len on containers",
+			args: args{
+				v: struct {
+					Three    []string       `validate:"len:3"`
+					Two      []string       `validate:"len:3"`
+					Map      map[string]int `validate:"len:1;value:min:0"`
+					Keys     map[string]int `validate:"key=len:2"`
+					Optional []int          `validate:"omitempty;len:2"`
+				}{
+					Three: []string{"a", "bb", "ccc"},
+					Two:   []string{"abc", "def"},
+					Map:   map[string]int{"a": 1, "b": -1},
+					Keys:  map[string]int{"ab": 1, "c": 2},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 4 &&
+					e[0].FieldName == "Two" && e[0].Err.Error() == "field invalidated: len:3 failed (length 2)" &&
+					e[1].FieldName == "Map" && e[1].Err.Error() == "field invalidated: len:1 failed (length 2)" &&
+					e[2].FieldName == "Map[b]" && e[3].FieldName == "Keys[c]"
+			},
+		},
+		{
+			name: "interface fields",
+			args: args{
+				v: struct {
+					Payload  any
+					PtrValue any
+					Nil      any
+					Required any `validate:"required"`
+					Scalar   any `validate:"min:5"`
+					Str      any `validate:"len:3"`
+					Time     any
+				}{
+					Payload:  Location{Code: "ab"},
+					PtrValue: &Base{ID: 0, Name: "abc"},
+					Scalar:   3,
+					Str:      "abc",
+					Time:     time.Now(),
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 4 &&
+					e[0].FieldName == "Payload.Code" && e[1].FieldName == "PtrValue.ID" &&
+					e[2].FieldName == "Required" && e[2].Err.Error() == "field invalidated: required failed" &&
+					e[3].FieldName == "Scalar" && e[3].Err.Error() == "field invalidated: min:5 failed (value 3)"
+			},
+		},
+		{
+			name: "interface fields: rules not fitting the value",
+			args: args{
+				v: struct {
+					Email  any `validate:"email"`
+					UUID   any `validate:"uuid"`
+					Before any `validate:"before:2020-01-01"`
+					Min    any `validate:"min:1.5"`
+					Fits   any `validate:"min:1.5"`
+				}{Email: 5, UUID: []int{1}, Before: "2019-01-01", Min: 3, Fits: 2.5},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && assert.Len(t, e, 4) &&
+					assert.EqualError(t, e[0].Err, "type not supported: email on int") &&
+					assert.EqualError(t, e[1].Err, "type not supported: uuid on int") &&
+					assert.EqualError(t, e[2].Err, "type not supported: before on string") &&
+					assert.EqualError(t, e[3].Err, "type not supported: min on int")
+			},
+		},
+		{
+			name: "in with spaced lists",
+			args: args{
+				v: struct {
+					Role    string  `validate:"in:admin, user, guest"`
+					Padded  string  `validate:"in: a , b "`
+					Spaced  string  `validate:"in:a, b"`
+					Code    int     `validate:"in:1, 2, 3"`
+					Count   uint    `validate:"notin: 4 , 5"`
+					Ratio   float64 `validate:"in:0.5, 1.5"`
+					Escaped string  `validate:"in:x\\, y, z"`
+				}{
+					Role:    "user",
+					Padded:  "b",
+					Spaced:  " b",
+					Code:    2,
+					Count:   5,
+					Ratio:   1.5,
+					Escaped: "x, y",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 2 &&
+					e[0].FieldName == "Spaced" && e[0].Err.Error() == `field invalidated: in:a, b failed (value " b")` &&
+					e[1].FieldName == "Count"
+			},
+		},
+		{
+			name: "exclusive and inclusive integer bounds",
+			args: args{
+				v: struct {
+					MinEq    int    `validate:"min:5"`
+					MaxEq    int    `validate:"max:5"`
+					GtEq     int    `validate:"gt:5"`
+					GtAbove  int    `validate:"gt:5"`
+					GteEq    int    `validate:"gte:5"`
+					GteBelow int8   `validate:"gte:5"`
+					LtEq     int    `validate:"lt:5"`
+					LteEq    int    `validate:"lte:5"`
+					LteAbove int64  `validate:"lte:5"`
+					UintGt   uint   `validate:"gt:0"`
+					UintLt   uint8  `validate:"lt:10"`
+					NegLt    uint16 `validate:"lt:-1"`
+					NegGte   uint32 `validate:"gte:-1"`
+					HugeGt   int64  `validate:"gt:9223372036854775808"`
+					HugeLt   int64  `validate:"lt:9223372036854775808"`
+				}{
+					MinEq:    5,
+					MaxEq:    5,
+					GtEq:     5,
+					GtAbove:  6,
+					GteEq:    5,
+					GteBelow: 4,
+					LtEq:     5,
+					LteEq:    5,
+					LteAbove: 6,
+					UintGt:   0,
+					UintLt:   9,
+					HugeLt:   9223372036854775807,
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				if !errors.As(err, &e) {
+					return false
+				}
+				names := make([]string, len(e))
+				for i, ve := range e {
+					names[i] = ve.FieldName
+				}
+				return assert.Equal(t, []string{"GtEq", "GteBelow", "LtEq", "LteAbove", "UintGt", "NegLt", "HugeGt"}, names) &&
+					e[0].Err.Error() == "field invalidated: gt:5 failed (value 5)"
+			},
+		},
+		{
+			name: "optional pointer bounds",
+			args: args{
+				v: func() any {
+					young, adult := 16, 18
+					var limit uint = 200
+					return struct {
+						Unset    *int  `validate:"min:18;max:130;gt:0;in:18,21"`
+						Young    *int  `validate:"min:18"`
+						Adult    *int  `validate:"min:18;max:130"`
+						Range    *int  `validate:"range:18-130"`
+						Limit    *uint `validate:"lte:100"`
+						Required *int  `validate:"required;min:18"`
+					}{
+						Young: &young,
+						Adult: &adult,
+						Range: &young,
+						Limit: &limit,
+					}
+				}(),
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 4 &&
+					e[0].FieldName == "Young" && e[0].Err.Error() == "field invalidated: min:18 failed (value 16)" &&
+					e[1].FieldName == "Range" && e[2].FieldName == "Limit" &&
+					e[3].FieldName == "Required" && e[3].Err.Error() == "field invalidated: required failed"
+			},
+		},
+		{
+			name: "character classes",
+			args: args{
+				v: struct {
+					Alpha        string   `validate:"alpha"`
+					Unicode      string   `validate:"alpha"`
+					AlphaDigit   string   `validate:"alpha"`
+					Alnum        string   `validate:"alphanumeric"`
+					AlnumSpace   string   `validate:"alphanumeric"`
+					Numeric      string   `validate:"numeric"`
+					NumericSign  string   `validate:"numeric"`
+					EmptyNumeric string   `validate:"numeric"`
+					OptNumeric   string   `validate:"omitempty;numeric"`
+					Codes        []string `validate:"numeric"`
+				}{
+					Alpha:       "abcXYZ",
+					Unicode:     "héllo",
+					AlphaDigit:  "abc1",
+					Alnum:       "abc123",
+					AlnumSpace:  "abc 123",
+					Numeric:     "0123",
+					NumericSign: "-1",
+					Codes:       []string{"1", "x"},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 5 &&
+					e[0].FieldName == "AlphaDigit" && e[0].Err.Error() == `field invalidated: alpha failed (value "abc1")` &&
+					e[1].FieldName == "AlnumSpace" && e[2].FieldName == "NumericSign" &&
+					e[3].FieldName == "EmptyNumeric" && e[4].FieldName == "Codes"
+			},
+		},
+		{
+			name: "byte and rune fields",
+			args: args{
+				v: struct {
+					Byte      byte   `validate:"min:1;max:127"`
+					ByteIn    byte   `validate:"in:10,13"`
+					ByteHigh  byte   `validate:"max:127"`
+					Rune      rune   `validate:"min:-1"`
+					RuneLow   rune   `validate:"min:-1"`
+					RuneIn    rune   `validate:"in:65,66"`
+					RuneRange rune   `validate:"range:-10-10"`
+					Bytes     []byte `validate:"min:32"`
+					Runes     []rune `validate:"notin:0"`
+				}{
+					Byte:      'a',
+					ByteIn:    '\n',
+					ByteHigh:  200,
+					Rune:      -1,
+					RuneLow:   -2,
+					RuneIn:    'C',
+					RuneRange: -11,
+					Bytes:     []byte("a\tb"),
+					Runes:     []rune{'x', 0},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 6 &&
+					e[0].FieldName == "ByteHigh" && e[0].Err.Error() == "field invalidated: max:127 failed (value 200)" &&
+					e[1].FieldName == "RuneLow" && e[1].Err.Error() == "field invalidated: min:-1 failed (value -2)" &&
+					e[2].FieldName == "RuneIn" && e[2].Err.Error() == "field invalidated: in:65,66 failed (value 67)" &&
+					e[3].FieldName == "RuneRange" && e[4].FieldName == "Bytes" && e[5].FieldName == "Runes"
+			},
+		},
+		{
+			name: "password",
+			args: args{
+				v: struct {
+					Strong    string `validate:"password:min=8,upper=1,digit=1,special=1"`
+					Short     string `validate:"password:min=8,upper=1,digit=1,special=1"`
+					NoUpper   string `validate:"password:min=8,upper=1,digit=1,special=1"`
+					NoSpecial string `validate:"password:min=8, upper=1, digit=1, special=1"`
+					Lower     string `validate:"password:lower=3"`
+				}{
+					Strong:    "Passw0rd!",
+					Short:     "Pa0!",
+					NoUpper:   "passw0rd!",
+					NoSpecial: "Passw0rdd",
+					Lower:     "ABCde",
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 4 &&
+					e[0].FieldName == "Short" && e[0].Err.Error() == "field invalidated: password:min=8,upper=1,digit=1,special=1 failed (min: 4 of 8)" &&
+					e[1].FieldName == "NoUpper" && e[1].Err.Error() == "field invalidated: password:min=8,upper=1,digit=1,special=1 failed (upper: 0 of 1)" &&
+					e[2].FieldName == "NoSpecial" && errors.Is(e[2].Err, ErrInvalidatedField) && !strings.Contains(e[2].Err.Error(), "Passw0rdd") &&
+					e[3].FieldName == "Lower" && e[3].Err.Error() == "field invalidated: password:lower=3 failed (lower: 2 of 3)"
+			},
+		},
+		{
+			name: "duplicate rules",
+			args: args{
+				v: struct {
+					Age    int            `validate:"min:3;min:5"`
+					Name   string         `validate:"len:2;required;len:2"`
+					Labels map[string]int `validate:"value:min:0;key=min:0"`
+				}{
+					Age:    4,
+					Name:   "ab",
+					Labels: map[string]int{"a": 1},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 2 &&
+					e[0].FieldName == "Age" && e[0].Err.Error() == `invalid validator syntax: "min:5"` &&
+					e[1].FieldName == "Name" && e[1].Err.Error() == `invalid validator syntax: "len:2"`
+			},
+		},
+		{
+			name: "bounds from fields",
+			args: args{
+				v: func() any {
+					limit := uint8(10)
+					return struct {
+						MinAge   int
+						MaxAge   *uint8
+						Age      int  `validate:"min:$MinAge;max:$MaxAge"`
+						Young    int8 `validate:"min:$MinAge"`
+						Count    uint `validate:"max:$MaxAge;min:1"`
+						NilBound uint `validate:"max:$Unset"`
+						Unset    *int
+						Literal  int    `validate:"min:18"`
+						Missing  int    `validate:"min:$Nope"`
+						NotInt   int    `validate:"max:$Name"`
+						Name     string `validate:"min:$MinAge"`
+					}{
+						MinAge:   18,
+						MaxAge:   &limit,
+						Age:      16,
+						Young:    20,
+						Count:    11,
+						NilBound: 100,
+						Literal:  18,
+						Name:     "a",
+					}
+				}(),
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 6 &&
+					e[0].FieldName == "Age" && e[0].Err.Error() == "field invalidated: min:18 failed (value 16)" &&
+					e[1].FieldName == "Age" && e[1].Err.Error() == "field invalidated: max:10 failed (value 16)" &&
+					e[2].FieldName == "Count" && e[2].Err.Error() == "field invalidated: max:10 failed (value 11)" &&
+					e[3].FieldName == "Missing" && e[3].Err.Error() == `invalid validator syntax: "min:$Nope"` &&
+					e[4].FieldName == "NotInt" && errors.Is(e[4].Err, ErrInvalidValidatorSyntax) &&
+					e[5].FieldName == "Name" && errors.Is(e[5].Err, ErrInvalidValidatorSyntax)
+			},
+		},
+		{
+			name: "uuid",
+			args: args{
+				v: struct {
+					Lower     string   `validate:"uuid"`
+					Upper     string   `validate:"uuid"`
+					NoHyphens string   `validate:"uuid"`
+					Braces    string   `validate:"uuid"`
+					BadHex    string   `validate:"uuid"`
+					Shifted   string   `validate:"uuid"`
+					IDs       []string `validate:"uuid"`
+				}{
+					Lower:     "123e4567-e89b-12d3-a456-426614174000",
+					Upper:     "123E4567-E89B-12D3-A456-426614174000",
+					NoHyphens: "123e4567e89b12d3a456426614174000",
+					Braces:    "{123e4567-e89b-12d3-a456-426614174000}",
+					BadHex:    "123e4567-e89b-12d3-a456-42661417400g",
+					Shifted:   "123e456-7e89b-12d3-a456-426614174000",
+					IDs:       []string{"00000000-0000-0000-0000-000000000000", ""},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 5 &&
+					e[0].FieldName == "NoHyphens" && e[0].Err.Error() == `field invalidated: uuid failed (value "123e4567e89b12d3a456426614174000")` &&
+					e[1].FieldName == "Braces" && e[2].FieldName == "BadHex" &&
+					e[3].FieldName == "Shifted" && e[4].FieldName == "IDs"
+			},
+		},
+		{
+			name: "oneof",
+			args: args{
+				v: struct {
+					Color   string   `validate:"oneof:red green blue"`
+					Bad     string   `validate:"oneof:red green blue"`
+					Spaces  string   `validate:"oneof:  red   green "`
+					Comma   string   `validate:"oneof:a,b c"`
+					Level   int      `validate:"oneof:1 2 3"`
+					Ratio   float64  `validate:"oneof:0.5 1.5"`
+					Palette []string `validate:"oneof:red green"`
+				}{
+					Color:   "green",
+					Bad:     "yellow",
+					Spaces:  "red",
+					Comma:   "a,b",
+					Level:   4,
+					Ratio:   0.5,
+					Palette: []string{"red", "blue"},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 3 &&
+					e[0].FieldName == "Bad" && e[0].Err.Error() == `field invalidated: oneof:red green blue failed (value "yellow")` &&
+					e[1].FieldName == "Level" && e[1].Err.Error() == "field invalidated: oneof:1 2 3 failed (value 4)" &&
+					e[2].FieldName == "Palette"
+			},
+		},
+		{
+			name: "map key and value targets",
+			args: args{
+				v: struct {
+					Scores  map[string]int `validate:"key:min:2;value:min:0"`
+					Limits  map[int]uint   `validate:"key=gt:0;value=max:10"`
+					NotMap  []int          `validate:"value:min:0"`
+					BadKey  map[string]int `validate:"key:min:x"`
+					Default map[string]int `validate:"min:0;min:1"`
+				}{
+					Scores: map[string]int{"a": 1, "bob": -1, "eve": 3},
+					Limits: map[int]uint{0: 5, 1: 11},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 7 &&
+					e[0].FieldName == "Scores[a]" && e[0].Err.Error() == `key: field invalidated: min:2 failed (length 1)` &&
+					e[1].FieldName == "Scores[bob]" && e[1].Err.Error() == "field invalidated: min:0 failed (value -1)" &&
+					e[2].FieldName == "Limits[0]" && errors.Is(e[2].Err, ErrInvalidatedField) && strings.HasPrefix(e[2].Err.Error(), "key: ") &&
+					e[3].FieldName == "Limits[1]" && e[3].Err.Error() == "field invalidated: max:10 failed (value 11)" &&
+					e[4].FieldName == "NotMap" && e[4].Err.Error() == `invalid validator syntax: "value:min:0"` &&
+					e[5].FieldName == "BadKey" && errors.Is(e[5].Err, ErrInvalidValidatorSyntax) &&
+					e[6].FieldName == "Default" && errors.Is(e[6].Err, ErrInvalidValidatorSyntax)
+			},
+		},
+		{
+			name: "slices of structs",
+			args: args{
+				v: struct {
+					Addresses []Address
+					Pointers  []*Location `validate:"minlen:1"`
+					Fixed     [2]Location
+					ByName    map[string]Location
+					Empty     []Address `validate:"minlen:1"`
+				}{
+					Addresses: []Address{
+						{Zip: "12345", City: Location{Code: "abc"}},
+						{Zip: "1", City: Location{Code: "ab"}},
+					},
+					Pointers: []*Location{nil, {Code: "x"}},
+					Fixed:    [2]Location{{Code: "abc"}, {Code: "abcd"}},
+					ByName:   map[string]Location{"b": {Code: "b"}, "a": {Code: "abc"}},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				if !errors.As(err, &e) {
+					return false
+				}
+				names := make([]string, len(e))
+				for i, ve := range e {
+					names[i] = ve.FieldName
+				}
+				return assert.Equal(t, []string{
+					"Addresses[1].Zip", "Addresses[1].City.Code",
+					"Pointers[1].Code", "Fixed[1].Code", "ByName[b].Code", "Empty",
+				}, names) && e[0].Err.Error() == "field invalidated: len:5 failed (length 1)"
+			},
+		},
+		{
+			name: "string value bounds",
+			args: args{
+				v: struct {
+					Above   string   `validate:"gte:abb"`
+					Equal   string   `validate:"gte:abb;lte:abb"`
+					Below   string   `validate:"gte:abb"`
+					Version string   `validate:"gt:v1.2;lt:v2"`
+					Old     string   `validate:"gt:v1.2"`
+					Length  string   `validate:"min:3;gte:b"`
+					Names   []string `validate:"lt:m"`
+				}{
+					Above:   "abc",
+					Equal:   "abb",
+					Below:   "aba",
+					Version: "v1.3",
+					Old:     "v1.2",
+					Length:  "ab",
+					Names:   []string{"alice", "zoe"},
+				},
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 5 &&
+					e[0].FieldName == "Below" && e[0].Err.Error() == `field invalidated: gte:abb failed (value "aba")` &&
+					e[1].FieldName == "Old" &&
+					e[2].FieldName == "Length" && e[2].Err.Error() == "field invalidated: min:3 failed (length 2)" &&
+					e[3].FieldName == "Length" && e[3].Err.Error() == `field invalidated: gte:b failed (value "ab")` &&
+					e[4].FieldName == "Names" && e[4].Err.Error() == `field invalidated: lt:m failed (value "zoe")`
+			},
+		},
+		{
+			name: "pointers to unsupported types",
+			args: args{
+				v: func() any {
+					u := uintptr(1)
+					n := 5
+					p := &n
+					ch := make(chan int)
+					return struct {
+						Uintptr    *uintptr   `validate:"min:1"`
+						NilUintptr *uintptr   `validate:"min:1"`
+						Required   *complex64 `validate:"required"`
+						Double     **int      `validate:"min:1"`
+						Chan       *chan int  `validate:"in:1,2"`
+						Func       *func()    `validate:"required;max:1"`
+					}{
+						Uintptr: &u,
+						Double:  &p,
+						Chan:    &ch,
+						Func:    new(func()),
+					}
+				}(),
+			},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				e := ValidationErrors{}
+				return errors.As(err, &e) && len(e) == 5 &&
+					e[0].FieldName == "Uintptr" && e[0].Err.Error() == "type not supported: uintptr" &&
+					e[1].FieldName == "Required" && errors.Is(e[1].Err, ErrInvalidatedField) &&
+					e[2].FieldName == "Double" && e[2].Err.Error() == "type not supported: *int" &&
+					e[3].FieldName == "Chan" && errors.Is(e[3].Err, ErrUnsupportedType) &&
+					e[4].FieldName == "Func" && errors.Is(e[4].Err, ErrUnsupportedType)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.args.v)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.True(t, tt.checkErr(err), "test expect an error, but got wrong error type")
+
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+}
+
+// failures returns the failures err holds, as their Error strings, and
+// stops the test if err is not ValidationErrors.
+func failures(t *testing.T, err error) []string {
+	t.Helper()
+	var e ValidationErrors
+	require.ErrorAs(t, err, &e)
+	got := make([]string, len(e))
+	for i, ve := range e {
+		got[i] = ve.Error()
+	}
+	return got
+}
+
+func TestValidateDive(t *testing.T) {
+	type order struct {
+		Qty    []int          `validate:"len:3;dive;min:1"`
+		Codes  []string       `validate:"min:1;max:2;dive;len:3"`
+		Ranks  [2]uint        `validate:"dive;max:9"`
+		Prices map[string]int `validate:"required;max:2;dive;min:1;key=len:3"`
+		Notes  *[]string      `validate:"omitempty;dive;prefix:#"`
+		Legacy []string       `validate:"len:2"`
+	}
+
+	notes := []string{"#a", "#b"}
+	assert.NoError(t, Validate(order{
+		Qty:    []int{1, 2, 3},
+		Codes:  []string{"abc"},
+		Ranks:  [2]uint{1, 9},
+		Prices: map[string]int{"eur": 1},
+		Notes:  &notes,
+		Legacy: []string{"abc", "d"},
+	}))
+
+	notes = []string{"#a", "b"}
+	got := failures(t, Validate(order{
+		Qty:    []int{0, 2},
+		Codes:  []string{"ab", "abc", "abcd"},
+		Ranks:  [2]uint{10, 1},
+		Prices: map[string]int{"eur": 0, "usd": 1, "gb": 1},
+		Notes:  &notes,
+		Legacy: []string{"ab"},
+	}))
+	assert.Equal(t, []string{
+		"[Qty]: field invalidated: len:3 failed (length 2)",
+		"[Qty]: field invalidated: min:1 failed (value 0)",
+		"[Codes]: field invalidated: max:2 failed (length 3)",
+		"[Codes]: field invalidated: len:3 failed (length 2)",
+		"[Codes]: field invalidated: len:3 failed (length 4)",
+		"[Ranks]: field invalidated: max:9 failed (value 10)",
+		"[Prices]: field invalidated: max:2 failed (length 3)",
+		"[Prices[eur]]: field invalidated: min:1 failed (value 0)",
+		"[Prices[gb]]: key: field invalidated: len:3 failed (length 2)",
+		`[Notes]: field invalidated: prefix:# failed (value "b")`,
+		"[Legacy]: field invalidated: len:2 failed (length 1)",
+	}, got)
+
+	var bad struct {
+		Name  string   `validate:"dive;len:3"`
+		Tags  []string `validate:"in:a,b;dive;len:1"`
+		IDs   []int    `validate:"dive;min:1;dive"`
+		Sizes []int    `validate:"min:x;dive;min:1"`
+	}
+	bad.Name = "abc"
+	var e ValidationErrors
+	require.ErrorAs(t, Validate(bad), &e)
+	got = got[:0]
+	for _, ve := range e {
+		assert.ErrorIs(t, ve.Err, ErrInvalidValidatorSyntax)
+		got = append(got, ve.FieldName)
+	}
+	assert.Equal(t, []string{"Name", "Tags", "IDs", "Sizes"}, got)
+}
+
+func TestValidateByteSize(t *testing.T) {
+	type upload struct {
+		Payload json.RawMessage `validate:"max:50"`
+		Avatar  []byte          `validate:"omitempty;min:4;max:10"`
+		Hash    *[]byte         `validate:"len:4"`
+		Flags   []byte          `validate:"len:2;dive;max:1"`
+	}
+
+	hash := []byte{1, 2, 3, 4}
+	assert.NoError(t, Validate(upload{Payload: json.RawMessage(`{"ok":true}`), Hash: &hash, Flags: []byte{0, 1}}))
+
+	short := []byte{1}
+	e := Validate(upload{
+		Payload: make(json.RawMessage, 100),
+		Avatar:  []byte{255, 255},
+		Hash:    &short,
+		Flags:   []byte{0, 2},
+	}).(ValidationErrors)
+	got := make([]string, len(e))
+	for i, ve := range e {
+		got[i] = ve.Error()
+	}
+	assert.Equal(t, []string{
+		"[Payload]: field invalidated: max:50 failed (length 100)",
+		"[Avatar]: field invalidated: min:4 failed (length 2)",
+		"[Hash]: field invalidated: len:4 failed (length 1)",
+		"[Flags]: field invalidated: max:1 failed (value 2)",
+	}, got)
+}
+
+func TestValidateMapSize(t *testing.T) {
+	type config struct {
+		Labels  map[string]string `validate:"min:1;max:3"`
+		Weights map[string]int    `validate:"max:2;value:min:1"`
+		Limits  *map[int]uint     `validate:"min:1"`
+	}
+
+	limits := map[int]uint{1: 0}
+	assert.NoError(t, Validate(config{Labels: map[string]string{"env": "prod"}, Weights: map[string]int{"a": 1}, Limits: &limits}))
+	// a nil pointer is an absent value, like for other rules
+	assert.NoError(t, Validate(config{Labels: map[string]string{"env": "prod"}}))
+
+	e := Validate(config{Labels: map[string]string{}, Weights: map[string]int{"a": 0, "b": 1, "c": 2}}).(ValidationErrors)
+	got := make([]string, len(e))
+	for i, ve := range e {
+		got[i] = ve.Error()
+	}
+	assert.Equal(t, []string{
+		"[Labels]: field invalidated: min:1 failed (length 0)",
+		"[Weights]: field invalidated: max:2 failed (length 3)",
+		"[Weights[a]]: field invalidated: min:1 failed (value 0)",
+	}, got)
+}
+
+type cleanName struct {
+	Name string `validate:"trim;min:2"`
+}
+
+type cleanProfile struct {
+	*cleanName
+	Title    string  `validate:"trim"`
+	Nick     *string `validate:"trim;omitempty;min:2"`
+	Raw      string
+	Home     cleanName
+	Work     *cleanName
+	Aliases  []cleanName
+	Friends  []*cleanName
+	ByLang   map[string]cleanName
+	Untagged []int
+	Next     *cleanProfile
+}
+
+func TestValidateAndClean(t *testing.T) {
+	nick := "  bo  "
+	orig := &cleanProfile{
+		cleanName: &cleanName{Name: " embedded "},
+		Title:     "  Dr  ",
+		Nick:      &nick,
+		Raw:       "  raw  ",
+		Home:      cleanName{Name: " home "},
+		Work:      &cleanName{Name: " work "},
+		Aliases:   []cleanName{{Name: " a1 "}},
+		Friends:   []*cleanName{{Name: " f1 "}, nil},
+		ByLang:    map[string]cleanName{"fr": {Name: " fr "}},
+	}
+	orig.Next = orig
+	before := *orig
+
+	cleaned, err := ValidateAndClean(orig)
+	assert.NoError(t, err)
+	c, ok := cleaned.(*cleanProfile)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	assert.Equal(t, "Dr", c.Title)
+	assert.Equal(t, "bo", *c.Nick)
+	assert.Equal(t, "  raw  ", c.Raw)
+	assert.Equal(t, "home", c.Home.Name)
+	assert.Equal(t, "work", c.Work.Name)
+	assert.Equal(t, "a1", c.Aliases[0].Name)
+	assert.Equal(t, "f1", c.Friends[0].Name)
+	assert.Nil(t, c.Friends[1])
+	assert.Nil(t, c.Untagged)
+	// not cleaned: map values and fields promoted through embedded pointers
+	assert.Equal(t, " fr ", c.ByLang["fr"].Name)
+	assert.Equal(t, " embedded ", c.Name)
+	// the cycle now goes through the copy
+	assert.Same(t, c, c.Next)
+
+	// the original is untouched
+	assert.Equal(t, before, *orig)
+	assert.Equal(t, "  bo  ", nick)
+	assert.Equal(t, " work ", orig.Work.Name)
+	assert.Equal(t, " a1 ", orig.Aliases[0].Name)
+	assert.Equal(t, " f1 ", orig.Friends[0].Name)
+
+	// a struct comes back as a struct, with the errors of the cleaned values
+	cleaned, err = ValidateAndClean(cleanName{Name: " x "})
+	assert.Equal(t, cleanName{Name: "x"}, cleaned)
+	e, ok := err.(ValidationErrors)
+	if assert.True(t, ok) {
+		assert.EqualError(t, e[0].Err, `field invalidated: min:2 failed (length 1)`)
+	}
+
+	cleaned, err = ValidateAndClean(42)
+	assert.Nil(t, cleaned)
+	assert.ErrorIs(t, err, ErrNotStruct)
+}
+
+func TestValidateRequiredIf(t *testing.T) {
+	type account struct {
+		Type    string
+		Seats   *int
+		Company string   `validate:"required_if:Type premium"`
+		VAT     string   `validate:"required_if:Seats 10"`
+		Admins  []string `validate:"required_if:Enabled true"`
+		Enabled bool
+	}
+
+	ten, two := 10, 2
+	assert.NoError(t, Validate(account{}))
+	assert.NoError(t, Validate(account{Type: "basic", Seats: &two}))
+	assert.NoError(t, Validate(account{Type: "premium", Company: "ACME", Seats: &ten, VAT: "FR123456", Enabled: true, Admins: []string{"root"}}))
+
+	e := Validate(account{Type: "premium", Seats: &ten, Enabled: true}).(ValidationErrors)
+	fields := make([]string, len(e))
+	for i, ve := range e {
+		fields[i] = ve.FieldName
+		assert.ErrorIs(t, ve.Err, ErrInvalidatedField)
+	}
+	assert.Equal(t, []string{"Company", "VAT", "Admins"}, fields)
+	assert.EqualError(t, e[0].Err, "field invalidated: required_if:Type premium failed")
+
+	var bad struct {
+		Missing string `validate:"required_if:Nope x"`
+		Kind    int
+		Number  string `validate:"required_if:Kind many"`
+		Tags    []string
+		Nested  string `validate:"required_if:Tags a"`
+	}
+	e = Validate(bad).(ValidationErrors)
+	assert.Len(t, e, 3)
+	for _, ve := range e {
+		assert.ErrorIs(t, ve.Err, ErrInvalidValidatorSyntax)
+	}
+}
+
+func TestValidateTrim(t *testing.T) {
+	type Code string
+	type form struct {
+		Name    string   `validate:"trim;min:3;max:5"`
+		Country string   `validate:"trim;len:2;in:FR,DE"`
+		Note    *string  `validate:"trim;omitempty;min:3"`
+		Code    Code     `validate:"trim;required"`
+		Untrim  string   `validate:"len:2"`
+		Late    string   `validate:"len:2;trim"`
+		Tags    []string `validate:"trim"`
+	}
+
+	blank := "   "
+	f := form{Name: "  bob  ", Country: " FR\t", Note: &blank, Code: " x ", Untrim: " a", Late: " a"}
+	e := Validate(f).(ValidationErrors)
+	fields := make([]string, len(e))
+	for i, ve := range e {
+		fields[i] = ve.FieldName
+	}
+	assert.Equal(t, []string{"Tags"}, fields)
+	assert.ErrorIs(t, e[0].Err, ErrInvalidValidatorSyntax)
+	// the caller's data is left untouched
+	assert.Equal(t, "  bob  ", f.Name)
+	assert.Equal(t, "   ", *f.Note)
+
+	f = form{Name: " bo ", Country: " FR ", Code: "  ", Untrim: " ab", Late: " ab "}
+	e = Validate(f).(ValidationErrors)
+	fields = fields[:0]
+	for _, ve := range e {
+		fields = append(fields, ve.FieldName)
+	}
+	assert.Equal(t, []string{"Name", "Code", "Untrim", "Late", "Tags"}, fields)
+}
+
+func TestValidateZeroBounds(t *testing.T) {
+	type bounds struct {
+		NonNeg   int     `validate:"min:0"`
+		NonPos   int8    `validate:"max:0"`
+		Ratio    float64 `validate:"min:0"`
+		Debt     float32 `validate:"max:0"`
+		Count    uint    `validate:"max:0"`
+		Balances []int64 `validate:"min:0"`
+	}
+
+	assert.NoError(t, Validate(bounds{}))
+	assert.NoError(t, Validate(bounds{NonPos: -1, Debt: -0.5, Balances: []int64{0, 1}}))
+
+	e := Validate(bounds{NonNeg: -1, NonPos: 1, Ratio: -0.1, Debt: 0.1, Count: 1, Balances: []int64{3, -2}}).(ValidationErrors)
+	fields := make([]string, len(e))
+	for i, ve := range e {
+		fields[i] = ve.FieldName
+		assert.ErrorIs(t, ve.Err, ErrInvalidatedField)
+	}
+	assert.Equal(t, []string{"NonNeg", "NonPos", "Ratio", "Debt", "Count", "Balances"}, fields)
+	assert.EqualError(t, e[0].Err, "field invalidated: min:0 failed (value -1)")
+}
+
+func TestValidateInLargeIntegers(t *testing.T) {
+	type ids struct {
+		Big      int64  `validate:"in:-1,4294967296,9223372036854775807"`
+		Small    int32  `validate:"notin:2147483648,-2147483648"`
+		Unsigned uint64 `validate:"in:-1,18446744073709551615"`
+		Mixed    uint   `validate:"notin:-5,5"`
+	}
+
+	assert.NoError(t, Validate(ids{Big: math.MaxInt32 + 2147483649, Small: math.MaxInt32, Unsigned: math.MaxUint64, Mixed: 4}))
+	assert.NoError(t, Validate(ids{Big: math.MaxInt64, Small: -2147483647, Unsigned: math.MaxUint64}))
+
+	e := Validate(ids{Big: math.MaxInt32 + 1, Small: math.MinInt32, Unsigned: 1, Mixed: 5}).(ValidationErrors)
+	got := make([]string, len(e))
+	for i, ve := range e {
+		got[i] = ve.Error()
+	}
+	assert.Equal(t, []string{
+		"[Big]: field invalidated: in:-1,4294967296,9223372036854775807 failed (value 2147483648)",
+		"[Small]: field invalidated: notin:2147483648,-2147483648 failed (value -2147483648)",
+		"[Unsigned]: field invalidated: in:-1,18446744073709551615 failed (value 1)",
+		"[Mixed]: field invalidated: notin:-5,5 failed (value 5)",
+	}, got)
+}
+
+func TestValidateNegativeArgs(t *testing.T) {
+	type temps struct {
+		Low   int     `validate:"min:-5"`
+		Delta int     `validate:"in:-1,0,1"`
+		Frost int8    `validate:"max:-1;notin:-2, -3"`
+		Ratio float64 `validate:"min:-1.5"`
+		Span  int     `validate:"range:-5--1"`
+		Step  int     `validate:"oneof:-1 -2"`
+		Count uint    `validate:"min:-1"`
+	}
+
+	assert.NoError(t, Validate(temps{Low: -3, Delta: -1, Frost: -1, Ratio: -1.5, Span: -5, Step: -2}))
+	assert.NoError(t, Validate(temps{Low: -5, Delta: 1, Frost: -4, Ratio: 0, Span: -1, Step: -1}))
+
+	e := Validate(temps{Low: -10, Delta: -2, Frost: -3, Ratio: -2, Span: 0, Step: 1}).(ValidationErrors)
+	fields := make([]string, len(e))
+	for i, ve := range e {
+		fields[i] = ve.FieldName
+		assert.ErrorIs(t, ve.Err, ErrInvalidatedField)
+	}
+	assert.Equal(t, []string{"Low", "Delta", "Frost", "Ratio", "Span", "Step"}, fields)
+	assert.EqualError(t, e[0].Err, "field invalidated: min:-5 failed (value -10)")
+	assert.EqualError(t, e[1].Err, "field invalidated: in:-1,0,1 failed (value -2)")
+}
+
+func TestValidateComplex(t *testing.T) {
+	type signal struct {
+		Root  complex128   `validate:"in:1+2i,(3-1i),0"`
+		Phase complex64    `validate:"required;notin:1i"`
+		Poles []complex128 `validate:"oneof:1 -1 1i -1i"`
+	}
+
+	assert.NoError(t, Validate(signal{Root: 3 - 1i, Phase: 1.1 + 2i, Poles: []complex128{1i, -1}}))
+
+	e := Validate(signal{Root: 1 + 3i, Phase: 1i, Poles: []complex128{2}}).(ValidationErrors)
+	assert.Len(t, e, 3)
+	for _, ve := range e {
+		assert.ErrorIs(t, ve.Err, ErrInvalidatedField)
+	}
+	assert.EqualError(t, e[0].Err, "field invalidated: in:1+2i,(3-1i),0 failed (value (1+3i))")
+
+	var bounded struct {
+		Z complex128 `validate:"min:0;max:1"`
+	}
+	e = Validate(bounded).(ValidationErrors)
+	assert.Len(t, e, 2)
+	assert.ErrorIs(t, e[0].Err, ErrInvalidValidatorSyntax)
+}
+
+func TestValidateErrorOrder(t *testing.T) {
+	type request struct {
+		Name string `validate:"min:3;in:abc,bob;prefix:x"`
+		Base
+		Age     int            `validate:"min:18;notin:16"`
+		Address *Address       `validate:"required"`
+		Tags    []string       `validate:"maxlen:1;in:a"`
+		Scores  map[string]int `validate:"value:min:0"`
+		Email   string         `validate:"email"`
+	}
+
+	e := Validate(request{
+		Name:    "a",
+		Age:     16,
+		Address: &Address{Zip: "1", City: Location{Code: "x"}},
+		Tags:    []string{"b", "a", "c"},
+		Scores:  map[string]int{"z": -1, "a": -2},
+	}).(ValidationErrors)
+
+	got := make([]string, len(e))
+	for i, ve := range e {
+		var re *RuleError
+		if errors.As(ve.Err, &re) {
+			got[i] = ve.FieldName + " " + re.Rule
+		}
+	}
+	assert.Equal(t, []string{
+		"Name min", "Name in", "Name prefix",
+		// Base.Name is shadowed by request.Name
+		"ID min",
+		"Age min", "Age notin",
+		"Address.Zip len", "Address.City.Code len",
+		"Tags maxlen", "Tags in", "Tags in",
+		"Scores[a] min", "Scores[z] min",
+		"Email email",
+	}, got)
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, e, Validate(request{
+			Name:    "a",
+			Age:     16,
+			Address: &Address{Zip: "1", City: Location{Code: "x"}},
+			Tags:    []string{"b", "a", "c"},
+			Scores:  map[string]int{"z": -1, "a": -2},
+		}))
+	}
+}
+
+func TestValidateFirst(t *testing.T) {
+	v := struct {
+		Name    string `validate:"len:3"`
+		Address Address
+		Tags    []string `validate:"in:a,b"`
+	}{
+		Name:    "ab",
+		Address: Address{Zip: "1"},
+		Tags:    []string{"c", "d"},
+	}
+
+	all := Validate(v)
+	assert.Len(t, all.(ValidationErrors), 5)
+
+	first := ValidateFirst(v)
+	e := ValidationErrors{}
+	assert.True(t, errors.As(first, &e))
+	assert.Equal(t, ValidationErrors{all.(ValidationErrors)[0]}, e)
+
+	v.Name = "abc"
+	e = ValidateFirst(v).(ValidationErrors)
+	assert.Len(t, e, 1)
+	assert.Equal(t, "Address.Zip", e[0].FieldName)
+
+	v.Address = Address{Zip: "12345", City: Location{Code: "abc"}}
+	e = ValidateFirst(v).(ValidationErrors)
+	assert.Len(t, e, 1)
+	assert.Equal(t, "Tags", e[0].FieldName)
+
+	v.Tags = nil
+	assert.NoError(t, ValidateFirst(v))
+}
+
+func TestValidateFirstStopsInElements(t *testing.T) {
+	visits := 0
+	v := New()
+	v.Register("odd", func(value reflect.Value, arg string) error {
+		visits++
+		if value.Int()%2 == 0 {
+			return ErrInvalidatedField
+		}
+		return nil
+	})
+	first := func(x any) error {
+		return validate(reflect.ValueOf(x), &walker{v: v, tagName: defaultTagName, firstOnly: true})
+	}
+
+	type item struct {
+		N int `validate:"odd"`
+	}
+	tests := []struct {
+		name string
+		x    any
+	}{
+		{name: "slice", x: struct {
+			Nums []int `validate:"odd"`
+		}{Nums: []int{2, 4, 6}}},
+		{name: "map", x: struct {
+			Nums map[string]int `validate:"odd"`
+		}{Nums: map[string]int{"a": 2, "b": 4, "c": 6}}},
+		{name: "struct slice", x: struct {
+			Items []item
+		}{Items: []item{{N: 2}, {N: 4}, {N: 6}}}},
+		{name: "struct map", x: struct {
+			Items map[string]item
+		}{Items: map[string]item{"a": {N: 2}, "b": {N: 4}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			visits = 0
+			assert.Error(t, first(tt.x))
+			assert.Equal(t, 1, visits)
+		})
+	}
+}
+
+func TestValidateSimple(t *testing.T) {
+	assert.NoError(t, ValidateSimple(Location{Code: "abc"}))
+
+	err := ValidateSimple(Address{Zip: "1", City: Location{Code: "ab"}})
+	assert.EqualError(t, err, "[Zip]: field invalidated: len:5 failed (length 1)\n"+
+		"[City.Code]: field invalidated: len:3 failed (length 2)")
+	e := ValidationErrors{}
+	assert.False(t, errors.As(err, &e))
+
+	assert.ErrorIs(t, ValidateSimple(42), ErrNotStruct)
+}
+
+func TestValidateHostileInputs(t *testing.T) {
+	var nilUser *Location
+	var nilIface error
+	user := &Location{}
+	inputs := []struct {
+		name string
+		v    any
+		msg  string
+	}{
+		{name: "nil", v: nil, msg: "got nil"},
+		{name: "nil interface", v: nilIface, msg: "got nil"},
+		{name: "typed nil pointer", v: nilUser, msg: "got nil pointer"},
+		{name: "pointer to pointer", v: &user, msg: "got ptr"},
+		{name: "nil map", v: map[string]int(nil), msg: "got map"},
+		{name: "channel", v: make(chan int), msg: "got chan"},
+		{name: "nil channel", v: (chan int)(nil), msg: "got chan"},
+		{name: "func", v: func() {}, msg: "got func"},
+		{name: "nil func", v: (func())(nil), msg: "got func"},
+		{name: "pointer to int", v: new(int), msg: "got int"},
+		{name: "slice", v: []Location{{}}, msg: "got slice"},
+		{name: "reflect.Value", v: reflect.ValueOf(Location{}), msg: ""},
+	}
+
+	for _, input := range inputs {
+		t.Run(input.name, func(t *testing.T) {
+			for name, fn := range map[string]func(any) error{
+				"Validate":       Validate,
+				"ValidateStruct": ValidateStruct,
+				"ValidateFirst":  ValidateFirst,
+				"ValidateFields": func(v any) error { return ValidateFields(v, "Code") },
+				"ValidateWith":   func(v any) error { return ValidateWith(v, Options{Deep: true}) },
+				"ValidateMutuallyExclusive": func(v any) error {
+					return ValidateMutuallyExclusive(v, "Code")
+				},
+			} {
+				var err error
+				assert.NotPanics(t, func() { err = fn(input.v) }, name)
+				if input.msg == "" {
+					// reflect.Value is a struct, just not the one meant
+					continue
+				}
+				assert.ErrorIs(t, err, ErrNotStruct, name)
+				assert.EqualError(t, err, "wrong argument given, should be a struct: "+input.msg, name)
+			}
+			assert.NotPanics(t, func() { _ = ValidateSlice(input.v) })
+			assert.NotPanics(t, func() { _ = CheckTags(input.v) })
+			assert.NotPanics(t, func() { _ = ValidateToMap(input.v) })
+		})
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	loc := Location{Code: "ab"}
+	var iface any = &loc
+	holder := struct{ Loc any }{Loc: loc}
+
+	for name, rv := range map[string]reflect.Value{
+		"struct":    reflect.ValueOf(loc),
+		"pointer":   reflect.ValueOf(&loc),
+		"interface": reflect.ValueOf(&iface).Elem(),
+		"field":     reflect.ValueOf(holder).Field(0),
+	} {
+		e, ok := ValidateValue(rv).(ValidationErrors)
+		if assert.True(t, ok, name) {
+			assert.Equal(t, "Code", e[0].FieldName, name)
+		}
+	}
+
+	assert.NoError(t, ValidateValue(reflect.ValueOf(Location{Code: "abc"})))
+	assert.ErrorIs(t, ValidateValue(reflect.Value{}), ErrNotStruct)
+	assert.ErrorIs(t, ValidateValue(reflect.ValueOf(42)), ErrNotStruct)
+	assert.ErrorIs(t, ValidateValue(reflect.ValueOf((*Location)(nil))), ErrNotStruct)
+	var nilIface any
+	assert.EqualError(t, ValidateValue(reflect.ValueOf(&nilIface).Elem()), "wrong argument given, should be a struct: got nil")
+}
+
+func TestMustValidate(t *testing.T) {
+	assert.NotPanics(t, func() { MustValidate(Location{Code: "abc"}) })
+
+	defer func() {
+		e, ok := recover().(ValidationErrors)
+		assert.True(t, ok)
+		assert.Len(t, e, 1)
+		assert.Equal(t, "Code", e[0].FieldName)
+	}()
+	MustValidate(Location{Code: "ab"})
+	t.Fatal("MustValidate did not panic")
+}
+
+func TestValidateSlice(t *testing.T) {
+	assert.NoError(t, ValidateSlice([]Location{{Code: "abc"}, {Code: "def"}}))
+	assert.NoError(t, ValidateSlice([]Location(nil)))
+
+	e := ValidationErrors{}
+	assert.True(t, errors.As(ValidateSlice([]*Address{
+		{Zip: "12345", City: Location{Code: "abc"}},
+		nil,
+		{Zip: "1", City: Location{Code: "ab"}},
+	}), &e))
+	assert.Len(t, e, 3)
+	assert.Equal(t, "[1]", e[0].FieldName)
+	assert.True(t, errors.Is(e[0].Err, ErrNotStruct))
+	assert.Equal(t, "[2].Zip", e[1].FieldName)
+	assert.Equal(t, "[2].City.Code", e[2].FieldName)
+
+	e = ValidateSlice(&[2]Location{{Code: "abc"}, {Code: "x"}}).(ValidationErrors)
+	assert.Len(t, e, 1)
+	assert.Equal(t, "[1].Code", e[0].FieldName)
+
+	err := ValidateSlice(Location{})
+	assert.True(t, errors.Is(err, ErrNotSlice))
+	assert.Equal(t, "wrong argument given, should be a slice or array: got struct", err.Error())
+}
+
+func TestValidateFields(t *testing.T) {
+	v := &struct {
+		Name    string `validate:"len:3"`
+		Age     int    `validate:"min:18"`
+		Email   string `validate:"email"`
+		Address Address
+	}{
+		Name:    "ab",
+		Age:     16,
+		Address: Address{Zip: "1", City: Location{Code: "x"}},
+	}
+
+	assert.Len(t, Validate(v).(ValidationErrors), 5)
+
+	e := ValidateFields(v, "Age", "Name").(ValidationErrors)
+	assert.Len(t, e, 2)
+	assert.Equal(t, "Name", e[0].FieldName)
+	assert.Equal(t, "Age", e[1].FieldName)
+
+	e = ValidateFields(v, "Address").(ValidationErrors)
+	assert.Len(t, e, 2)
+	assert.Equal(t, "Address.Zip", e[0].FieldName)
+	assert.Equal(t, "Address.City.Code", e[1].FieldName)
+
+	v.Age = 20
+	assert.NoError(t, ValidateFields(v, "Age"))
+	assert.NoError(t, ValidateFields(v))
+
+	err := ValidateFields(v, "Age", "Emial")
+	assert.True(t, errors.Is(err, ErrUnknownField))
+	assert.False(t, errors.As(err, &e))
+}
+
+func TestValidateMutuallyExclusive(t *testing.T) {
+	type contact struct {
+		Email string
+		Phone *string
+		Fax   int
+	}
+	phone := "555"
+
+	assert.NoError(t, ValidateMutuallyExclusive(contact{Email: "a@b.c"}, "Email", "Phone"))
+	assert.NoError(t, ValidateMutuallyExclusive(&contact{Phone: &phone, Fax: 1}, "Email", "Phone"))
+
+	e := ValidationErrors{}
+	assert.True(t, errors.As(ValidateMutuallyExclusive(contact{Email: "a@b.c", Phone: &phone}, "Email", "Phone"), &e))
+	assert.Len(t, e, 1)
+	assert.Equal(t, "Email,Phone", e[0].FieldName)
+	assert.True(t, errors.Is(e[0].Err, ErrInvalidatedField))
+	assert.Equal(t, "field invalidated: exactly one of Email, Phone must be set, got 2", e[0].Err.Error())
+
+	e = ValidateMutuallyExclusive(contact{}, "Email", "Phone", "Fax").(ValidationErrors)
+	assert.Equal(t, "field invalidated: exactly one of Email, Phone, Fax must be set, got 0", e[0].Err.Error())
+
+	err := ValidateMutuallyExclusive(contact{}, "Email", "Mobile")
+	assert.True(t, errors.Is(err, ErrUnknownField))
+	assert.Equal(t, `unknown field: "Mobile" in validator.contact`, err.Error())
+
+	type private struct {
+		Email string
+		phone string
+	}
+	err = ValidateMutuallyExclusive(private{phone: "555"}, "Email", "phone")
+	assert.ErrorIs(t, err, ErrValidateForUnexportedFields)
+
+	// a field promoted through a nil embedded pointer is not set
+	type audited struct {
+		*Audit
+		Email string
+	}
+	assert.NoError(t, ValidateMutuallyExclusive(audited{Email: "x"}, "By", "Email"))
+	assert.Error(t, ValidateMutuallyExclusive(audited{Audit: &Audit{By: "me"}, Email: "x"}, "By", "Email"))
+
+	assert.True(t, errors.Is(ValidateMutuallyExclusive("x", "Email"), ErrNotStruct))
+}
+
+func TestValidateContext(t *testing.T) {
+	v := struct {
+		Name    string `validate:"len:3"`
+		Address Address
+	}{
+		Name:    "ab",
+		Address: Address{Zip: "1"},
+	}
+
+	e := ValidationErrors{}
+	assert.True(t, errors.As(ValidateContext(context.Background(), v), &e))
+	assert.Equal(t, Validate(v), e)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := ValidateContext(ctx, v)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, errors.As(err, &e))
+
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	assert.ErrorIs(t, ValidateContext(ctx, &v), context.DeadlineExceeded)
+}
+
+func TestValidateWith(t *testing.T) {
+	v := struct {
+		Name    string `validate:"len:3"`
+		Address Address
+		Base    *Base
+		Payload any
+	}{
+		Name:    "ab",
+		Address: Address{Zip: "1"},
+		Base:    &Base{},
+		Payload: Location{},
+	}
+
+	deep := ValidateWith(v, Options{Deep: true}).(ValidationErrors)
+	assert.Equal(t, Validate(v), deep)
+	assert.Len(t, deep, 6)
+
+	e := ValidateWith(v, Options{}).(ValidationErrors)
+	assert.Len(t, e, 1)
+	assert.Equal(t, "Name", e[0].FieldName)
+
+	v.Name = "abc"
+	assert.NoError(t, ValidateWith(v, Options{}))
+}
+
+func TestBatchValidator(t *testing.T) {
+	records := []any{
+		Location{Code: "abc"},
+		&Address{Zip: "1", City: Location{Code: "x"}},
+		struct {
+			Name    string `validate:"len:3"`
+			Address Address
+		}{Name: "ab"},
+		42,
+	}
+
+	deep := NewBatchValidator(Options{Deep: true})
+	shallow := NewBatchValidator(Options{})
+	for _, r := range records {
+		assert.Equal(t, Validate(r), deep.Validate(r))
+		assert.Equal(t, ValidateWith(r, Options{}), shallow.Validate(r))
+	}
+
+	e := shallow.Validate(records[2]).(ValidationErrors)
+	assert.Len(t, e, 1)
+	assert.Equal(t, "Name", e[0].FieldName)
+
+	v := New()
+	v.Register("upper", func(value reflect.Value, arg string) error {
+		if value.String() != strings.ToUpper(value.String()) {
+			return ErrInvalidatedField
+		}
+		return nil
+	})
+	v.SetMessageFunc(func(ve ValidationError) string { return "bad " + ve.FieldName })
+	batch := v.NewBatch(Options{})
+	e = batch.Validate(struct {
+		Code string `validate:"upper"`
+	}{Code: "ab"}).(ValidationErrors)
+	assert.Len(t, e, 1)
+	assert.Equal(t, "bad Code", e[0].Message)
+}
+
+func TestValidateInlineStructs(t *testing.T) {
+	type product struct {
+		Meta struct {
+			Size int `validate:"min:1"`
+			Dims *struct {
+				Unit string `validate:"in:cm,in"`
+			}
+		}
+		Variants []struct {
+			SKU string `validate:"len:3"`
+		}
+	}
+
+	var p product
+	p.Meta.Dims = &struct {
+		Unit string `validate:"in:cm,in"`
+	}{Unit: "mm"}
+	p.Variants = []struct {
+		SKU string `validate:"len:3"`
+	}{{SKU: "abc"}, {SKU: "ab"}}
+
+	e := Validate(p).(ValidationErrors)
+	fields := make([]string, len(e))
+	for i, ve := range e {
+		fields[i] = ve.FieldName
+		assert.ErrorIs(t, ve.Err, ErrInvalidatedField)
+	}
+	assert.Equal(t, []string{"Meta.Size", "Meta.Dims.Unit", "Variants[1].SKU"}, fields)
+
+	p.Meta.Size, p.Meta.Dims.Unit, p.Variants[1].SKU = 1, "cm", "abd"
+	assert.NoError(t, Validate(p))
+	assert.NoError(t, CheckTags(p))
+}
+
+type node struct {
+	Value    int `validate:"min:1"`
+	Next     *node
+	Children []node
+	Any      any
+}
+
+func TestValidateCycles(t *testing.T) {
+	self := &node{}
+	self.Next = self
+	e := Validate(self).(ValidationErrors)
+	assert.Len(t, e, 1)
+	assert.Equal(t, "Value", e[0].FieldName)
+
+	a, b := &node{Value: 1}, &node{}
+	a.Next, b.Next = b, a
+	b.Any = a
+	e = Validate(a).(ValidationErrors)
+	assert.Len(t, e, 1)
+	assert.Equal(t, "Next.Value", e[0].FieldName)
+
+	tree := make([]node, 1)
+	tree[0].Children = tree
+	e = Validate(&tree[0]).(ValidationErrors)
+	assert.Len(t, e, 1)
+}
+
+func TestValidateMaxDepth(t *testing.T) {
+	list := func(n int) *node {
+		head := &node{Value: 1}
+		for cur := head; n > 1; n-- {
+			cur.Next = &node{Value: 1}
+			cur = cur.Next
+		}
+		return head
+	}
+
+	assert.NoError(t, Validate(list(DefaultMaxDepth)))
+	err := Validate(list(DefaultMaxDepth + 1))
+	assert.ErrorIs(t, err, ErrMaxDepth)
+	assert.EqualError(t, err, "maximum nesting depth exceeded: more than 32 levels at validator.node")
+
+	assert.NoError(t, ValidateWith(list(100), Options{Deep: true, MaxDepth: 100}))
+	assert.ErrorIs(t, ValidateWith(list(3), Options{Deep: true, MaxDepth: 2}), ErrMaxDepth)
+	assert.NoError(t, ValidateWith(list(3), Options{MaxDepth: 1}))
+	assert.NoError(t, ValidateWith(list(3), Options{Deep: true, MaxDepth: -1}))
+}
+
+func TestValidateWithSkipDefaults(t *testing.T) {
+	type config struct {
+		Host    string  `validate:"prefix:https://" default:"localhost"`
+		Port    *int    `validate:"min:1024" default:"80"`
+		Ratio   float64 `validate:"max:1" default:"1.5"`
+		Debug   bool    `validate:"eq:false" default:"true"`
+		Retries uint    `validate:"required;min:1" default:"0"`
+		Name    string  `validate:"len:3"`
+	}
+	port := 80
+	c := config{Host: "localhost", Port: &port, Ratio: 1.5, Debug: true}
+
+	assert.Len(t, Validate(c).(ValidationErrors), 7)
+
+	e := ValidateWith(c, Options{Deep: true, SkipDefaults: true}).(ValidationErrors)
+	assert.Len(t, e, 2)
+	assert.Equal(t, "Retries", e[0].FieldName)
+	assert.Equal(t, "field invalidated: required failed", e[0].Err.Error())
+	assert.Equal(t, "Name", e[1].FieldName)
+
+	port = 81
+	c.Host = "http://example.com"
+	e = ValidateWith(c, Options{SkipDefaults: true}).(ValidationErrors)
+	assert.Len(t, e, 4)
+	assert.Equal(t, "Host", e[0].FieldName)
+	assert.Equal(t, "Port", e[1].FieldName)
+}
+
+func TestSupportedRules(t *testing.T) {
+	// one failing struct per rule shows that the rule is dispatched and not
+	// merely accepted by the syntax check
+	failing := map[string]any{
+		"required": struct {
+			F string `validate:"required"`
+		}{},
+		"omitempty": struct {
+			F string `validate:"omitempty;len:3"`
+		}{F: "ab"},
+		"minlen": struct {
+			F []int `validate:"minlen:2"`
+		}{F: []int{1}},
+		"maxlen": struct {
+			F []int `validate:"maxlen:1"`
+		}{F: []int{1, 2}},
+		"in": struct {
+			F string `validate:"in:a,b"`
+		}{F: "c"},
+		"notin": struct {
+			F int `validate:"notin:1,2"`
+		}{F: 1},
+		"len": struct {
+			F string `validate:"len:3"`
+		}{F: "ab"},
+		"min": struct {
+			F int `validate:"min:3"`
+		}{F: 2},
+		"max": struct {
+			F uint `validate:"max:3"`
+		}{F: 4},
+		"range": struct {
+			F int `validate:"range:1-3"`
+		}{F: 4},
+		"gt": struct {
+			F int `validate:"gt:3"`
+		}{F: 3},
+		"gte": struct {
+			F int `validate:"gte:3"`
+		}{F: 2},
+		"lt": struct {
+			F int `validate:"lt:3"`
+		}{F: 3},
+		"lte": struct {
+			F int `validate:"lte:3"`
+		}{F: 4},
+		"runemin": struct {
+			F string `validate:"runemin:3"`
+		}{F: "éé"},
+		"runemax": struct {
+			F string `validate:"runemax:1"`
+		}{F: "éé"},
+		"regexp": struct {
+			F string `validate:"regexp:^[0-9]+$"`
+		}{F: "a1"},
+		"contains": struct {
+			F string `validate:"contains:@"`
+		}{F: "a"},
+		"prefix": struct {
+			F string `validate:"prefix:v"`
+		}{F: "1.0"},
+		"suffix": struct {
+			F string `validate:"suffix:.go"`
+		}{F: "main.rs"},
+		"eq": struct {
+			F bool `validate:"eq:true"`
+		}{},
+		"before": struct {
+			F time.Time `validate:"before:2020-01-01"`
+		}{F: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		"after": struct {
+			F time.Time `validate:"after:2020-01-01"`
+		}{},
+		"email": struct {
+			F string `validate:"email"`
+		}{F: "nobody"},
+		"eqfield": struct {
+			F string `validate:"eqfield:G"`
+			G string
+		}{F: "a", G: "b"},
+		"nefield": struct {
+			F string `validate:"nefield:G"`
+			G string
+		}{F: "a", G: "a"},
+		"gtfield": struct {
+			F int `validate:"gtfield:G"`
+			G int
+		}{F: 1, G: 2},
+		"ltfield": struct {
+			F int `validate:"ltfield:G"`
+			G int
+		}{F: 2, G: 1},
+		"url": struct {
+			F string `validate:"url"`
+		}{F: "not a url"},
+		"alpha": struct {
+			F string `validate:"alpha"`
+		}{F: "a1"},
+		"alphanumeric": struct {
+			F string `validate:"alphanumeric"`
+		}{F: "a-1"},
+		"numeric": struct {
+			F string `validate:"numeric"`
+		}{F: "1a"},
+		"password": struct {
+			F string `validate:"password:min=8,digit=1"`
+		}{F: "password"},
+		"uuid": struct {
+			F string `validate:"uuid"`
+		}{F: "1234"},
+		"oneof": struct {
+			F string `validate:"oneof:red green"`
+		}{F: "blue"},
+		"trim": struct {
+			F string `validate:"trim;len:4"`
+		}{F: " ab "},
+		"dive": struct {
+			F []string `validate:"dive;len:2"`
+		}{F: []string{"abc", "de"}},
+		"required_if": struct {
+			F string `validate:"required_if:G b"`
+			G string
+		}{G: "b"},
+	}
+
+	rules := SupportedRules()
+	assert.Len(t, rules, len(failing))
+	assert.True(t, sort.StringsAreSorted(rules))
+	for _, name := range rules {
+		v, ok := failing[name]
+		if !assert.True(t, ok, "no failing case for %s", name) {
+			continue
+		}
+		if name == "omitempty" {
+			assert.ErrorIs(t, Validate(v), ErrInvalidatedField, name)
+			assert.NoError(t, Validate(reflect.Zero(reflect.TypeOf(v)).Interface()), name)
+			continue
+		}
+		err := Validate(v)
+		assert.ErrorIs(t, err, ErrInvalidatedField, name)
+		assert.NotErrorIs(t, err, ErrInvalidValidatorSyntax, name)
+	}
+}
+
+func TestSupportedKinds(t *testing.T) {
+	kinds := SupportedKinds()
+	assert.Contains(t, kinds, reflect.String)
+	assert.Contains(t, kinds, reflect.Uint8)
+	assert.Contains(t, kinds, reflect.Float64)
+	assert.Contains(t, kinds, reflect.Map)
+	assert.NotContains(t, kinds, reflect.Struct)
+	assert.Contains(t, kinds, reflect.Complex128)
+	assert.NotContains(t, kinds, reflect.Chan)
+}
+
+func TestCheckTags(t *testing.T) {
+	type node struct {
+		Value int `validate:"min:x"`
+		Next  *node
+	}
+	type request struct {
+		Name string `validate:"len:3"`
+		Age  int    `validate:"min:1;max:foo;in:a"`
+		Home Address
+		Work *struct {
+			Zip string `validate:"len"`
+		}
+		Tree   node
+		hidden string `validate:"bogus"`
+	}
+
+	assert.NoError(t, CheckTags(Address{}))
+	assert.NoError(t, CheckTags(&Location{}))
+
+	e := ValidationErrors{}
+	assert.True(t, errors.As(CheckTags(request{}), &e))
+	assert.True(t, errors.Is(e, ErrInvalidValidatorSyntax))
+	assert.Len(t, e, 4)
+	assert.Equal(t, "Age", e[0].FieldName)
+	assert.Equal(t, `invalid validator syntax: "max:foo"`, e[0].Err.Error())
+	assert.Equal(t, "Age", e[1].FieldName)
+	assert.Equal(t, "Work.Zip", e[2].FieldName)
+	assert.Equal(t, "Tree.Value", e[3].FieldName)
+
+	assert.True(t, errors.Is(CheckTags(42), ErrNotStruct))
+	assert.True(t, errors.Is(CheckTags(nil), ErrNotStruct))
+}
+
+func TestCheckTagsNested(t *testing.T) {
+	type item struct {
+		SKU   string `validate:"len:x"`
+		Count int    `validate:"min:1"`
+	}
+	type order struct {
+		Base
+		Items    []item
+		ByID     map[string]*item
+		Fixed    [2]item
+		Billing  item
+		Shipping item
+		Times    []time.Time `validate:"after:2020-01-01"`
+	}
+
+	e := CheckTags((*order)(nil)).(ValidationErrors)
+	names := make([]string, len(e))
+	for i, ve := range e {
+		names[i] = ve.FieldName
+		assert.True(t, errors.Is(ve.Err, ErrInvalidValidatorSyntax))
+	}
+	// a type used by several fields is reported for each of them
+	assert.Equal(t, []string{"Items[].SKU", "ByID[].SKU", "Fixed[].SKU", "Billing.SKU", "Shipping.SKU"}, names)
+
+	assert.NoError(t, CheckTags((*Base)(nil)))
+
+	// before and after are for time.Time, not every struct
+	type event struct {
+		At    time.Time `validate:"before:2030-01-01"`
+		Where Address   `validate:"required;before:2030-01-01"`
+	}
+	e = ValidationErrors{}
+	assert.True(t, errors.As(CheckTags(event{}), &e))
+	if assert.Len(t, e, 1) {
+		assert.Equal(t, "Where", e[0].FieldName)
+		assert.EqualError(t, e[0].Err, `invalid validator syntax: "before:2030-01-01"`)
+	}
+}
+
+func TestValidatorCheckTags(t *testing.T) {
+	type account struct {
+		IBAN string `validate:"iban"`
+		Name string `validate:"min_len:2"`
+	}
+	assert.ErrorIs(t, CheckTags(account{}), ErrUnknownRule)
+
+	v := New()
+	v.Register("iban", func(reflect.Value, string) error { return nil })
+	v.Deprecate("min_len", "runemin")
+	assert.NoError(t, v.CheckTags(account{}))
+	assert.ErrorIs(t, v.CheckTags(42), ErrNotStruct)
+}
+
+func TestValidateWithJSONNames(t *testing.T) {
+	type item struct {
+		SKU string `json:"sku" validate:"len:3"`
+	}
+	v := struct {
+		UserName string  `json:"user_name,omitempty" validate:"min:3"`
+		Age      int     `json:",omitempty" validate:"min:18"`
+		Secret   string  `json:"-" validate:"len:4"`
+		Dash     string  `json:"-," validate:"len:4"`
+		Plain    string  `validate:"len:2"`
+		Home     Address `json:"home"`
+		Items    []item  `json:"items"`
+	}{UserName: "ab", Age: 16, Home: Address{Zip: "1", City: Location{Code: "abc"}}, Items: []item{{SKU: "x"}}}
+
+	e := ValidateWith(v, Options{Deep: true, JSONNames: true}).(ValidationErrors)
+	names := make([]string, len(e))
+	for i, ve := range e {
+		names[i] = ve.FieldName
+	}
+	assert.Equal(t, []string{"user_name", "Age", "Secret", "-", "Plain", "home.Zip", "items[0].sku"}, names)
+
+	e = Validate(v).(ValidationErrors)
+	assert.Equal(t, "UserName", e[0].FieldName)
+}
+
+func TestValidateWithTag(t *testing.T) {
+	v := struct {
+		Name string `validate:"len:3" args:"min:5"`
+		Age  int    `args:"min:18"`
+	}{
+		Name: "abc",
+		Age:  16,
+	}
+
+	assert.NoError(t, Validate(v))
+	assert.NoError(t, ValidateWithTag(v, ""))
+
+	e := ValidationErrors{}
+	assert.True(t, errors.As(ValidateWithTag(v, "args"), &e))
+	assert.Len(t, e, 2)
+	assert.Equal(t, "Name", e[0].FieldName)
+	assert.Equal(t, "Age", e[1].FieldName)
+}
+
+func TestValidatorZeroValue(t *testing.T) {
+	var v Validator
+	type request struct {
+		Count int    `validate:"min:3"`
+		Mail  string `validate:"required"`
+		Tags  []int  `validate:"minlen:1;dive;in:1,2"`
+	}
+	err := v.Validate(request{Count: 1, Tags: []int{3}})
+	assert.EqualError(t, err, "[Count]: field invalidated: min:3 failed (value 1)\n"+
+		"[Mail]: field invalidated: required failed\n"+
+		"[Tags]: field invalidated: in:1,2 failed (value 3)\n")
+	assert.NoError(t, v.Validate(request{Count: 3, Mail: "a", Tags: []int{1}}))
+}
+
+func TestValidatorRegisterWalkerRule(t *testing.T) {
+	v := New()
+	ok := func(reflect.Value, string) error { return nil }
+	for _, name := range []string{"required", "omitempty", "minlen", "maxlen", "trim", "dive", "required_if", "eqfield"} {
+		assert.Panics(t, func() { v.Register(name, ok) }, name)
+	}
+	assert.NotPanics(t, func() { v.Register("len", ok) })
+}
+
+func TestValidatorRegister(t *testing.T) {
+	v := New()
+	v.Register("even", func(value reflect.Value, arg string) error {
+		if value.Int()%2 != 0 {
+			return ErrInvalidatedField
+		}
+		return nil
+	})
+	v.Register("prefix", func(value reflect.Value, arg string) error {
+		if !strings.HasPrefix(value.String(), strings.ToUpper(arg)) {
+			return ErrInvalidatedField
+		}
+		return nil
+	})
+
+	type request struct {
+		Count  int    `validate:"even;min:2"`
+		Counts []int  `validate:"even"`
+		Code   string `validate:"prefix:ab"`
+	}
+
+	assert.NoError(t, v.Validate(request{Count: 4, Counts: []int{2, 6}, Code: "ABC"}))
+
+	e := ValidationErrors{}
+	assert.True(t, errors.As(v.Validate(request{Count: 3, Counts: []int{2, 5}, Code: "abc"}), &e))
+	assert.Len(t, e, 3)
+	assert.Equal(t, "Count", e[0].FieldName)
+	assert.Equal(t, "Counts", e[1].FieldName)
+	assert.Equal(t, "Code", e[2].FieldName)
+
+	// rules registered on v do not leak into the package-level Validate
+	assert.True(t, errors.As(Validate(request{Count: 4, Code: "abc"}), &e))
+	assert.Len(t, e, 2)
+	assert.True(t, errors.Is(e[0].Err, ErrInvalidValidatorSyntax))
+	assert.True(t, errors.Is(e[1].Err, ErrInvalidValidatorSyntax))
+}
+
+func TestValidateSkipsUnexportedFields(t *testing.T) {
+	v := New()
+	// Interface panics on values obtained through unexported fields
+	v.Register("iface", func(value reflect.Value, arg string) error {
+		_ = value.Interface()
+		return nil
+	})
+
+	type inner struct {
+		Name string `validate:"iface;len:10"`
+	}
+	type request struct {
+		Name   string `validate:"iface"`
+		inner  inner
+		ptr    *inner
+		values []inner
+	}
+
+	r := request{Name: "ok", inner: inner{Name: "x"}, ptr: &inner{Name: "y"}, values: []inner{{Name: "z"}}}
+	assert.NotPanics(t, func() {
+		assert.NoError(t, v.Validate(r))
+		assert.NoError(t, v.Validate(&r))
+	})
+}
+
+func TestRuleError(t *testing.T) {
+	e := Validate(struct {
+		Age  int    `validate:"min:18"`
+		Name string `validate:"len:3"`
+		Code string `validate:"required"`
+	}{Age: 16, Name: "ab"}).(ValidationErrors)
+	assert.Len(t, e, 3)
+
+	var re *RuleError
+	assert.True(t, errors.As(e[0].Err, &re))
+	assert.Equal(t, &RuleError{Rule: "min", Arg: "18", Value: int64(16)}, re)
+	assert.True(t, errors.Is(e[0].Err, ErrInvalidatedField))
+
+	assert.True(t, errors.As(e[1].Err, &re))
+	assert.Equal(t, "len", re.Rule)
+	assert.Equal(t, "3", re.Arg)
+	assert.Equal(t, 2, re.Value)
+	assert.Equal(t, "field invalidated: len:3 failed (length 2)", re.Error())
+
+	assert.True(t, errors.As(e[2].Err, &re))
+	assert.Equal(t, &RuleError{Rule: "required"}, re)
+	assert.Equal(t, "field invalidated: required failed", re.Error())
+}
+
+func TestValidationErrorsFilter(t *testing.T) {
+	e := Validate(struct {
+		Name    string `validate:"min:3;in:abc,bob"`
+		Age     int    `validate:"min:18"`
+		Address Address
+	}{Name: "a", Age: 20, Address: Address{Zip: "1", City: Location{Code: "abc"}}}).(ValidationErrors)
+
+	name := e.Filter("Name")
+	assert.Len(t, name, 2)
+	assert.Equal(t, ValidationErrors{e[0], e[1]}, name)
+	assert.True(t, e.HasField("Name"))
+
+	assert.Equal(t, ValidationErrors{e[2]}, e.Filter("Address.Zip"))
+	assert.True(t, e.HasField("Address.Zip"))
+
+	assert.Nil(t, e.Filter("Age"))
+	assert.False(t, e.HasField("Age"))
+	assert.False(t, e.HasField("Address"))
+	assert.Nil(t, ValidationErrors(nil).Filter("Name"))
+}
+
+func TestValidationErrorsIsAs(t *testing.T) {
+	err := Validate(struct {
+		Name string `validate:"len:x"`
+		Age  int    `validate:"min:18"`
+		Code string `validate:"in:a,b"`
+	}{Age: 16, Code: "c"})
+
+	assert.True(t, errors.Is(err, ErrInvalidatedField))
+	assert.True(t, errors.Is(err, ErrInvalidValidatorSyntax))
+	assert.False(t, errors.Is(err, ErrUnsupportedType))
+	assert.False(t, errors.Is(Validate(Location{Code: "abc"}), ErrInvalidatedField))
+
+	var re *RuleError
+	assert.True(t, errors.As(err, &re))
+	assert.Equal(t, "min", re.Rule)
+
+	// the elements stay accessible as before
+	e := ValidationErrors{}
+	assert.True(t, errors.As(err, &e))
+	assert.Len(t, e, 3)
+
+	// and so does the first one on its own
+	var ve ValidationError
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, "Name", ve.FieldName)
+	assert.False(t, errors.As(Validate(Location{Code: "abc"}), &ve))
+}
+
+func TestValidationErrorUnwrap(t *testing.T) {
+	e := Validate(struct {
+		Age  int    `validate:"min:18"`
+		Code string `validate:"in:a,b" validate_msg:"unknown code"`
+		Name string `validate:"len:x"`
+	}{Age: 16, Code: "c"}).(ValidationErrors)
+
+	assert.ErrorIs(t, e[0], ErrInvalidatedField)
+	assert.NotErrorIs(t, e[0], ErrInvalidValidatorSyntax)
+	assert.ErrorIs(t, e[2], ErrInvalidValidatorSyntax)
+	var re *RuleError
+	assert.True(t, errors.As(e[1], &re))
+	assert.Equal(t, "in", re.Rule)
+
+	assert.EqualError(t, e[0], "[Age]: field invalidated: min:18 failed (value 16)")
+	assert.EqualError(t, e[1], "[Code]: unknown code")
+	assert.Equal(t, e[0].Err, e[0].Unwrap())
+}
+
+// Timestamp mimics a type from another package: exported fields with tags
+// next to unexported internals.
+type Timestamp struct {
+	wall   uint64
+	ext    int64
+	loc    *time.Location
+	Label  string `validate:"len:3"`
+	Offset int    `validate:"range:-12-14"`
+}
+
+type auditLog struct {
+	Timestamp
+	Created  Timestamp
+	Updated  *Timestamp
+	Deadline time.Time `validate:"after:2020-01-01"`
+	inner    Timestamp
+}
+
+func TestValidateForeignTypes(t *testing.T) {
+	v := New()
+	v.Register("iface", func(value reflect.Value, arg string) error {
+		_ = value.Interface()
+		return nil
+	})
+	log := auditLog{
+		Timestamp: Timestamp{wall: 1, Label: "abc"},
+		Created:   Timestamp{ext: 2, Label: "ab", Offset: 15},
+		Updated:   &Timestamp{loc: time.UTC, Label: "abc"},
+		Deadline:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		inner:     Timestamp{Label: "x"},
+	}
+
+	var err error
+	assert.NotPanics(t, func() { err = Validate(&log) })
+	e := ValidationErrors{}
+	assert.True(t, errors.As(err, &e))
+	assert.Len(t, e, 2)
+	assert.Equal(t, "Created.Label", e[0].FieldName)
+	assert.Equal(t, "Created.Offset", e[1].FieldName)
+
+	assert.NotPanics(t, func() {
+		assert.NoError(t, v.Validate(struct {
+			Stamp Timestamp `validate:"iface"`
+			At    time.Time `validate:"iface"`
+		}{Stamp: Timestamp{wall: 1, Label: "abc"}}))
+	})
+}
+
+func TestValidationErrorsMarshalJSON(t *testing.T) {
+	err := Validate(struct {
+		Age   int      `validate:"min:18"`
+		Name  string   `validate:"len:x"`
+		Ch    chan int `validate:"min:1"`
+		email string   `validate:"required"`
+	}{Age: 16})
+
+	data, jerr := json.Marshal(err)
+	assert.NoError(t, jerr)
+	assert.JSONEq(t, `[
+		{"field": "Age", "kind": "invalid", "error": "field invalidated: min:18 failed (value 16)"},
+		{"field": "Name", "kind": "syntax", "error": "invalid validator syntax: \"len:x\""},
+		{"field": "Ch", "kind": "unsupported", "error": "type not supported: chan int"},
+		{"field": "email", "kind": "unexported", "error": "validation for unexported field is not allowed"}
+	]`, string(data))
+
+	data, jerr = json.Marshal(ValidationErrors(nil))
+	assert.NoError(t, jerr)
+	assert.Equal(t, "[]", string(data))
+}
+
+func TestValidateMessageTag(t *testing.T) {
+	err := Validate(struct {
+		Password string   `validate:"min:8" validate_msg:"Password too short"`
+		Tags     []string `validate:"in:a,b" validate_msg:"Unknown tag"`
+		Age      int      `validate:"min:x" validate_msg:"Too young"`
+		Name     string   `validate:"len:3"`
+	}{Password: "abc", Tags: []string{"a", "c"}, Name: "ab"})
+
+	e := ValidationErrors{}
+	assert.True(t, errors.As(err, &e))
+	assert.Len(t, e, 4)
+	assert.Equal(t, "Password too short", e[0].Message)
+	assert.True(t, errors.Is(e[0].Err, ErrInvalidatedField))
+	assert.Equal(t, "Unknown tag", e[1].Message)
+	// syntax errors keep their message, they are meant for the developer
+	assert.Empty(t, e[2].Message)
+	assert.Empty(t, e[3].Message)
+	assert.Equal(t, "[Password]: Password too short\n"+
+		"[Tags]: Unknown tag\n"+
+		"[Age]: invalid validator syntax: \"min:x\"\n"+
+		"[Name]: field invalidated: len:3 failed (length 2)\n", e.Error())
+
+	data, jerr := json.Marshal(e[:1])
+	assert.NoError(t, jerr)
+	assert.JSONEq(t, `[{"field": "Password", "kind": "invalid", "error": "Password too short"}]`, string(data))
+
+	// the companion tag follows the tag name given to ValidateWithTag
+	e = ValidateWithTag(struct {
+		Age int `args:"min:18" args_msg:"Too young"`
+	}{Age: 16}, "args").(ValidationErrors)
+	assert.Equal(t, "Too young", e[0].Message)
+}
+
+func TestValidateToMap(t *testing.T) {
+	assert.Equal(t, map[string][]string{
+		"Name": {
+			"field invalidated: min:3 failed (length 1)",
+			"field invalidated: in:abc,bob failed (value \"a\")",
+		},
+		"Address.Zip": {"field invalidated: len:5 failed (length 1)"},
+	}, ValidateToMap(struct {
+		Name    string `validate:"min:3;in:abc,bob"`
+		Age     int    `validate:"min:18"`
+		Address Address
+	}{
+		Name:    "a",
+		Age:     20,
+		Address: Address{Zip: "1", City: Location{Code: "abc"}},
+	}))
+
+	assert.Nil(t, ValidateToMap(Address{Zip: "12345", City: Location{Code: "abc"}}))
+	assert.Equal(t, map[string][]string{"": {"wrong argument given, should be a struct: got int"}}, ValidateToMap(42))
+}
+
+func TestValidatorDeprecate(t *testing.T) {
+	v := New()
+	v.Deprecate("min_len", "minlen")
+	v.Deprecate("is_in", "in")
+	v.Deprecate("max_len", "maxlen")
+
+	type form struct {
+		Tags  []string `validate:"min_len:2"`
+		Color string   `validate:"required;is_in:red,green"`
+		Size  int      `validate:"max:3"`
+	}
+	assert.Empty(t, v.Deprecations())
+
+	assert.NoError(t, v.Validate(form{Tags: []string{"a", "b"}, Color: "red"}))
+	e := v.Validate(form{Tags: []string{"a"}, Color: "blue"}).(ValidationErrors)
+	assert.Len(t, e, 2)
+	assert.EqualError(t, e[0].Err, "field invalidated: minlen:2 failed (length 1)")
+	assert.EqualError(t, e[1].Err, `field invalidated: in:red,green failed (value "blue")`)
+	assert.Equal(t, []string{"is_in", "min_len"}, v.Deprecations())
+
+	// aliases only exist on the Validator they were declared on
+	e = Validate(form{Tags: []string{"a", "b"}, Color: "red"}).(ValidationErrors)
+	assert.ErrorIs(t, e[0].Err, ErrUnknownRule)
+}
+
+func TestValidatorSetMessageFunc(t *testing.T) {
+	v := New()
+	type form struct {
+		Code string `validate:"len:3" validate_msg:"bad code"`
+		Zip  string `validate:"len:5"`
+		City Location
+	}
+	f := form{Code: "ab", Zip: "1", City: Location{Code: "x"}}
+	assert.Equal(t, "[Code]: bad code\n[Zip]: field invalidated: len:5 failed (length 1)\n[City.Code]: field invalidated: len:3 failed (length 1)\n", v.Validate(f).Error())
+
+	v.SetMessageFunc(func(ve ValidationError) string {
+		switch {
+		case ve.Message != "":
+			return "übersetzt: " + ve.Message
+		case ve.FieldName == "Zip":
+			return ""
+		}
+		return "ungültig"
+	})
+	e := v.Validate(f).(ValidationErrors)
+	assert.Equal(t, "übersetzt: bad code", e[0].Message)
+	assert.Equal(t, "", e[1].Message)
+	assert.Equal(t, "ungültig", e[2].Message)
+	assert.ErrorIs(t, e[2].Err, ErrInvalidatedField)
+
+	// the package-level functions are untouched
+	assert.Equal(t, "", Validate(f).(ValidationErrors)[1].Message)
+
+	v.SetMessageFunc(nil)
+	assert.Equal(t, "", v.Validate(f).(ValidationErrors)[2].Message)
+}
+
+func ExampleValidator_SetMessageFunc() {
+	v := New()
+	v.SetMessageFunc(func(ve ValidationError) string {
+		var ruleErr *RuleError
+		if errors.As(ve.Err, &ruleErr) {
+			return fmt.Sprintf("Feld ungültig: Regel %s:%s nicht erfüllt", ruleErr.Rule, ruleErr.Arg)
+		}
+		return ""
+	})
 
+	fmt.Print(v.Validate(Location{Code: "ab"}))
+	// Output: [Code]: Feld ungültig: Regel len:3 nicht erfüllt
 }